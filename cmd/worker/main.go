@@ -6,13 +6,16 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // quiet hours timezone on images without zoneinfo
 
 	rmqLib "github.com/RehanAthallahAzhar/tokohobby-messaging/rabbitmq"
 	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/configs"
 	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/messaging"
 	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/repositories"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/routing"
 	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/senders"
 	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/services"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/templates"
 	"github.com/sirupsen/logrus"
 )
 
@@ -69,48 +72,268 @@ func main() {
 	}
 	defer db.Close()
 
-	// Initialize repository
+	// Initialize repositories
 	notifRepo := repositories.NewNotificationRepository(db, logger)
+	followerRepo := repositories.NewFollowerRepository(db, logger)
+	watcherRepo := repositories.NewProductWatcherRepository(db, logger)
+	followupRepo := repositories.NewFollowupRepository(db, logger)
+	scheduledRepo := repositories.NewScheduledNotificationRepository(db, logger)
+	preferenceRepo := repositories.NewPreferenceRepository(db, logger)
+	waSessionRepo := repositories.NewWhatsAppSessionRepository(db, logger)
+	webhookRepo := repositories.NewWebhookRepository(db, logger)
+	deviceRepo := repositories.NewDeviceTokenRepository(db, logger)
 
-	// Initialize senders (mock mode)
-	var emailSender, pushSender senders.Sender
+	// Initialize senders. Channels with a configured provider use it; in
+	// mock mode the others fall back to mock senders, otherwise they are
+	// left unregistered.
+	var pushSender senders.Sender
 
 	if cfg.MockMode {
-		logger.Info("Using MOCK senders (demo mode)")
-		emailSender = senders.NewMockEmailSender(logger)
+		logger.Info("Using MOCK senders for unconfigured channels (demo mode)")
 		pushSender = senders.NewMockPushSender(logger)
 	} else {
-		logger.Info("Using REAL senders (production mode)")
-		// TODO: Initialize real senders
-		// emailSender = senders.NewSMTPSender(smtpConfig, logger)
-		// pushSender = senders.NewFCMSender(fcmConfig, logger)
-		logger.Fatal("Real senders not implemented yet")
+		logger.Info("Using REAL senders only (production mode)")
+	}
+
+	// Every provider gets its own rate limit, timeout and circuit breaker
+	guard := func(sender senders.Sender) senders.Sender {
+		if sender == nil {
+			return nil
+		}
+		return senders.NewGuardedSender(sender, cfg.SenderGuard, logger)
+	}
+
+	// Email goes through SMTP when a relay is configured, with an optional
+	// fallback relay chained behind it
+	var emailSender, emailFallback senders.Sender
+	switch {
+	case cfg.SMTP.Host != "":
+		emailSender, err = senders.NewSMTPSender(cfg.SMTP, cfg.Email.FromAddress, logger)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to initialize SMTP relay")
+		}
+		if cfg.SMTPFallback.Host != "" {
+			emailFallback, err = senders.NewSMTPSender(cfg.SMTPFallback, cfg.Email.FromAddress, logger)
+			if err != nil {
+				logger.WithError(err).Fatal("Failed to initialize fallback SMTP relay")
+			}
+		}
+	case cfg.MockMode:
+		emailSender = senders.NewMockEmailSender(cfg.Email.FromAddress, logger)
+	}
+
+	// SMS goes through the HTTP gateway when one is configured, with an
+	// optional fallback gateway chained behind it
+	var smsSender, smsFallback senders.Sender
+	switch {
+	case cfg.SMS.GatewayURL != "":
+		smsSender, err = senders.NewHTTPSMSSender(cfg.SMS, logger)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to initialize SMS gateway")
+		}
+		if cfg.SMSFallback.GatewayURL != "" {
+			smsFallback, err = senders.NewHTTPSMSSender(cfg.SMSFallback, logger)
+			if err != nil {
+				logger.WithError(err).Fatal("Failed to initialize fallback SMS gateway")
+			}
+		}
+	case cfg.MockMode:
+		smsSender = senders.NewMockSMSSender(cfg.SMS.MaxSegments, logger)
+	}
+
+	// WhatsApp goes through the Cloud API when credentials are configured
+	var waSender senders.Sender
+	switch {
+	case cfg.WhatsApp.PhoneNumberID != "":
+		waSender, err = senders.NewWhatsAppSender(cfg.WhatsApp, waSessionRepo, logger)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to initialize WhatsApp sender")
+		}
+	case cfg.MockMode:
+		waSender = senders.NewMockWhatsAppSender(waSessionRepo, logger)
+	}
+
+	// Browsers get Web Push when VAPID keys are configured, in every mode
+	var webPushSender senders.Sender
+	if cfg.WebPush.VAPIDPrivateKey != "" {
+		webPushSender, err = senders.NewWebPushSender(cfg.WebPush, deviceRepo, logger)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to initialize Web Push sender")
+		}
+	}
+
+	// iOS devices get pushes from APNs directly when a .p8 key is configured
+	var apnsSender senders.Sender
+	if cfg.APNs.KeyFile != "" {
+		apnsSender, err = senders.NewAPNsSender(cfg.APNs, deviceRepo, notifRepo, logger)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to initialize APNs sender")
+		}
+	}
+
+	// Webhooks are delivered to the subscribers' own endpoints in every mode
+	webhookSender := senders.NewWebhookSender(cfg.Webhook, webhookRepo, logger)
+
+	// Ops alerts go to Slack and Telegram when configured
+	var slackSender, telegramSender senders.Sender
+	switch {
+	case cfg.Ops.SlackWebhookURL != "":
+		slackSender, err = senders.NewSlackSender(cfg.Ops.SlackWebhookURL, cfg.Ops.Timeout, logger)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to initialize Slack sender")
+		}
+	case cfg.MockMode:
+		slackSender = senders.NewMockChatSender("slack", logger)
+	}
+	switch {
+	case cfg.Ops.TelegramBotToken != "":
+		telegramSender, err = senders.NewTelegramSender(cfg.Ops, logger)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to initialize Telegram sender")
+		}
+	case cfg.MockMode:
+		telegramSender = senders.NewMockChatSender("telegram", logger)
+	}
+
+	// The mock push stands in for FCM in the demo, unless a real push
+	// backend is configured
+	if webPushSender != nil || apnsSender != nil {
+		pushSender = nil
+	}
+
+	// Senders are looked up by the channel named in their GetType; channels
+	// left unconfigured (nil) are not registered. Webhooks are not guarded
+	// as every subscriber is its own endpoint, with its own retries.
+	// Providers named in a channel's failover chain are tried in that order
+	// instead of all receiving the notification.
+	providers, err := senders.ChainProviders([]senders.Sender{
+		guard(emailSender),
+		guard(emailFallback),
+		guard(pushSender),
+		guard(webPushSender),
+		guard(apnsSender),
+		guard(smsSender),
+		guard(smsFallback),
+		guard(waSender),
+		webhookSender,
+		guard(slackSender),
+		guard(telegramSender),
+	}, cfg.Failover.Chains, cfg.Failover.FailureThreshold, cfg.Failover.Cooldown, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to build failover chains")
+	}
+	senderRegistry := senders.NewRegistry(providers...)
+	logger.WithField("channels", senderRegistry.Channels()).Info("Senders registered")
+
+	// Load and validate templates against event schemas
+	tmplRegistry := templates.NewRegistry()
+	if err := messaging.RegisterTemplates(tmplRegistry); err != nil {
+		logger.WithError(err).Fatal("Failed to load notification templates")
+	}
+
+	emailLayout, err := templates.NewEmailLayout(cfg.Email.BrandName, cfg.Email.BaseURL)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to load email layout")
+	}
+
+	// Preferences are only applied when enforcement is switched on
+	var prefFilter *services.PreferenceFilter
+	if cfg.EnforcePreferences {
+		quietHoursLocation, err := time.LoadLocation(cfg.QuietHoursTimezone)
+		if err != nil {
+			logger.WithError(err).Fatal("Invalid quiet hours timezone")
+		}
+		prefFilter = services.NewPreferenceFilter(preferenceRepo, quietHoursLocation)
 	}
 
 	// Initialize notification service
-	notifService := services.NewNotificationService(notifRepo, emailSender, pushSender, logger)
+	notifService := services.NewNotificationService(notifRepo, scheduledRepo, prefFilter, senderRegistry, tmplRegistry, emailLayout, cfg.Dispatch.Parallelism, cfg.Dispatch.Deadline, logger)
 
-	// Initialize consumers
-	orderConsumer := messaging.NewOrderEventConsumer(rmq, notifService, logger)
-	blogConsumer := messaging.NewBlogEventConsumer(rmq, notifService, logger)
+	// Load routing rules and validate them against event schemas
+	ruleSet, err := routing.LoadRuleSet(cfg.RoutingRulesFile, messaging.DefaultRules)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to load routing rules")
+	}
 
-	// Start consumers with context
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	routingEngine, err := routing.NewEngine(ruleSet, tmplRegistry, messaging.EventSchemas(), notifService, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid routing rules")
+	}
 
-	// Start order consumer
-	go func() {
-		if err := orderConsumer.Start(ctx); err != nil {
-			logger.WithError(err).Error("Order consumer error")
+	logger.WithField("event_types", routingEngine.EventTypes()).Info("Routing rules loaded")
+
+	// Fan-out workers deliver notifications to large audiences in batches
+	fanoutService := services.NewFanoutService(
+		notifService,
+		cfg.Fanout.BatchSize,
+		cfg.Fanout.QueueSize,
+		cfg.Fanout.Workers,
+		cfg.Fanout.BatchDelay,
+		logger,
+	)
+
+	// Notification scheduler sends notifications created with a future SendAt
+	notifScheduler := services.NewNotificationScheduler(
+		scheduledRepo,
+		notifService,
+		cfg.Scheduler.PollInterval,
+		cfg.Scheduler.BatchSize,
+		logger,
+	)
+
+	// Follow-up scheduler runs delayed order actions such as payment reminders
+	followupScheduler := services.NewFollowupScheduler(
+		followupRepo,
+		cfg.Followups.PollInterval,
+		cfg.Followups.BatchSize,
+		logger,
+	)
+
+	// Register consumers
+	consumers := messaging.NewConsumerRegistry(rmq, logger)
+	orderHandler := messaging.NewOrderEventHandler(
+		notifService,
+		followupScheduler,
+		cfg.Followups.PaymentReminderIntervals,
+		cfg.Followups.ReviewRequestDelay,
+		logger,
+	)
+	orderHandler.RegisterFollowups()
+	blogHandler := messaging.NewBlogEventHandler(notifService, fanoutService, followerRepo, logger)
+	productHandler := messaging.NewProductEventHandler(fanoutService, watcherRepo, logger)
+
+	for _, spec := range []messaging.ConsumerSpec{
+		orderHandler.Spec(routingEngine),
+		blogHandler.Spec(routingEngine),
+		productHandler.Spec(routingEngine),
+		messaging.ShipmentConsumerSpec(routingEngine),
+		messaging.AccountConsumerSpec(routingEngine),
+		messaging.NewWhatsAppEventHandler(waSessionRepo, logger).Spec(),
+		messaging.NewWebhookEventHandler(webhookRepo, logger).Spec(),
+		messaging.NewDeviceEventHandler(deviceRepo, logger).Spec(),
+	} {
+		consumerCfg, ok := cfg.Consumers[spec.Name]
+		if !ok {
+			logger.WithField("consumer", spec.Name).Fatal("Missing consumer config")
+		}
+		if !consumerCfg.Enabled {
+			logger.WithField("consumer", spec.Name).Info("Consumer disabled by config")
+			continue
 		}
-	}()
 
-	// Start blog consumer
-	go func() {
-		if err := blogConsumer.Start(ctx); err != nil {
-			logger.WithError(err).Error("Blog consumer error")
+		if err := consumers.Register(spec.WithConfig(consumerCfg)); err != nil {
+			logger.WithError(err).Fatal("Failed to register consumer")
 		}
-	}()
+	}
+
+	// Start consumers with context
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fanoutService.Start(ctx)
+	followupScheduler.Start(ctx)
+	notifScheduler.Start(ctx)
+	consumers.Start(ctx)
 
 	logger.Info("Notification worker is running. Waiting for events... (Press Ctrl+C to exit)")
 
@@ -123,7 +346,20 @@ func main() {
 	cancel()
 
 	// Give workers time to finish
-	time.Sleep(2 * time.Second)
+	stopped := make(chan struct{})
+	go func() {
+		consumers.Wait()
+		fanoutService.Wait()
+		followupScheduler.Wait()
+		notifScheduler.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		logger.Warn("Timed out waiting for consumers to stop")
+	}
 
 	logger.Info("Notification worker stopped gracefully")
 }
@@ -0,0 +1,2 @@
+-- Priority assigned by routing rules (low, normal, high)
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS priority VARCHAR(10) NOT NULL DEFAULT 'normal';
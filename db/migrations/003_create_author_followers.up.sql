@@ -0,0 +1,9 @@
+-- Followers of blog authors, maintained from follow/unfollow events
+CREATE TABLE IF NOT EXISTS author_followers (
+    author_id UUID NOT NULL,
+    follower_id UUID NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (author_id, follower_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_follower_authors ON author_followers(follower_id);
@@ -0,0 +1,14 @@
+-- Users watching a product for restock or price drop alerts
+CREATE TABLE IF NOT EXISTS product_watchers (
+    product_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    kind VARCHAR(20) NOT NULL, -- restock, price_drop
+
+    -- Only alert price drops at or below this price (NULL = any drop)
+    target_price NUMERIC(15, 2),
+
+    created_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (product_id, kind, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_watched_products ON product_watchers(user_id);
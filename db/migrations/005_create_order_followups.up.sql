@@ -0,0 +1,24 @@
+-- Delayed, cancellable follow-ups for orders (e.g. payment reminders)
+CREATE TABLE IF NOT EXISTS order_followups (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id VARCHAR(64) NOT NULL,
+    user_id UUID NOT NULL,
+    kind VARCHAR(50) NOT NULL,
+    due_at TIMESTAMP NOT NULL,
+
+    -- Data needed to render the follow-up notification
+    payload JSONB DEFAULT '{}'::jsonb,
+
+    -- pending, processing, sent, cancelled, failed
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT DEFAULT 0,
+    last_error TEXT,
+
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+
+    UNIQUE (order_id, kind, due_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_due_followups ON order_followups(due_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_order_followups ON order_followups(order_id, kind);
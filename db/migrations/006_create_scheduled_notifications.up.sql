@@ -0,0 +1,20 @@
+-- Notifications to be sent at a later time (CreateNotificationRequest.SendAt)
+CREATE TABLE IF NOT EXISTS scheduled_notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    send_at TIMESTAMP NOT NULL,
+
+    -- The serialized notification request
+    request JSONB NOT NULL,
+
+    -- pending, processing, sent, cancelled, failed
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT DEFAULT 0,
+    last_error TEXT,
+
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_due_scheduled_notifications ON scheduled_notifications(send_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_user_scheduled_notifications ON scheduled_notifications(user_id, send_at);
@@ -0,0 +1,6 @@
+-- Notifications sharing a collapse key replace each other instead of stacking
+-- (e.g. one live shipment tracking notification per order)
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS collapse_key VARCHAR(128);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_collapse_key
+    ON notifications(user_id, collapse_key) WHERE collapse_key IS NOT NULL;
@@ -0,0 +1,7 @@
+-- Last inbound WhatsApp message per phone number. Free-form messages are only
+-- allowed within 24 hours of it; otherwise an approved template is required.
+CREATE TABLE IF NOT EXISTS whatsapp_sessions (
+    phone_number VARCHAR(20) PRIMARY KEY,
+    last_inbound_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT NOW()
+);
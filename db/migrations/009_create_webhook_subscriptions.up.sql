@@ -0,0 +1,23 @@
+-- Outgoing webhook endpoints registered by partner stores and internal tools
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+
+    -- Notification types to deliver; empty means all
+    types TEXT[] NOT NULL DEFAULT '{}',
+
+    -- Disabled automatically after repeated failed deliveries
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    consecutive_failures INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    disabled_at TIMESTAMP,
+
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+
+    UNIQUE (user_id, url)
+);
+
+CREATE INDEX IF NOT EXISTS idx_active_webhooks ON webhook_subscriptions(user_id) WHERE active = TRUE;
@@ -0,0 +1,25 @@
+-- Push targets of a user's devices: FCM and APNs tokens, and browser
+-- Web Push subscriptions
+CREATE TABLE IF NOT EXISTS device_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    platform VARCHAR(20) NOT NULL, -- fcm, apns, web
+
+    -- Device token, or the push service endpoint for web
+    token TEXT NOT NULL,
+
+    -- Web Push subscription keys (base64url)
+    p256dh TEXT,
+    auth TEXT,
+
+    -- Deactivated when the push service reports the token as gone
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    last_used_at TIMESTAMP,
+
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+
+    UNIQUE (platform, token)
+);
+
+CREATE INDEX IF NOT EXISTS idx_active_device_tokens ON device_tokens(user_id, platform) WHERE active = TRUE;
@@ -0,0 +1,13 @@
+-- One row per channel sender a notification went through, recording the
+-- provider that delivered it (or failed last when failover was exhausted)
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    notification_id UUID NOT NULL REFERENCES notifications(id) ON DELETE CASCADE,
+    channel VARCHAR(20) NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL, -- sent, partial, skipped, deferred, failed
+    error TEXT,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries ON notification_deliveries(notification_id);
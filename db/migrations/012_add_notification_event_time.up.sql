@@ -0,0 +1,4 @@
+-- When the event behind a collapsing notification happened. A notification
+-- only replaces the one with the same collapse key if its event is not older,
+-- so a late tracking update cannot overwrite a newer status.
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS event_time TIMESTAMPTZ;
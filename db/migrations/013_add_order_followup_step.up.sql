@@ -0,0 +1,16 @@
+-- Identify follow-ups by their step (e.g. the first or second payment
+-- reminder) instead of their due time, which retries move. Otherwise a
+-- redelivered event schedules a reminder again once the first one was
+-- retried.
+ALTER TABLE order_followups ADD COLUMN IF NOT EXISTS step INT NOT NULL DEFAULT 0;
+
+UPDATE order_followups f
+SET step = numbered.step
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY order_id, kind ORDER BY created_at, due_at) - 1 AS step
+    FROM order_followups
+) numbered
+WHERE f.id = numbered.id;
+
+ALTER TABLE order_followups DROP CONSTRAINT IF EXISTS order_followups_order_id_kind_due_at_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_order_followups_step ON order_followups(order_id, kind, step);
@@ -4,16 +4,42 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type AppConfig struct {
-	Env        string
-	ServerPort string
-	LogLevel   string
-	Database   DatabaseConfig
-	RabbitMQ   RabbitMQConfig
-	MockMode   bool
+	Env              string
+	ServerPort       string
+	LogLevel         string
+	Database         DatabaseConfig
+	RabbitMQ         RabbitMQConfig
+	Email            EmailConfig
+	SMTP             SMTPConfig
+	SMTPFallback     SMTPConfig
+	SMS              SMSConfig
+	SMSFallback      SMSConfig
+	Failover         FailoverConfig
+	SenderGuard      SenderGuardConfig
+	WhatsApp         WhatsAppConfig
+	Webhook          WebhookConfig
+	Ops              OpsConfig
+	WebPush          WebPushConfig
+	APNs             APNsConfig
+	Consumers        map[string]ConsumerConfig
+	Fanout           FanoutConfig
+	Dispatch         DispatchConfig
+	Followups        FollowupConfig
+	Scheduler        SchedulerConfig
+	RoutingRulesFile string
+	MockMode         bool
+
+	// EnforcePreferences applies users' opt-outs and quiet hours to
+	// non-critical notifications
+	EnforcePreferences bool
+
+	// QuietHoursTimezone is the IANA zone users' quiet hours are set in
+	QuietHoursTimezone string
 }
 
 type DatabaseConfig struct {
@@ -33,8 +59,155 @@ type RabbitMQConfig struct {
 	ReconnectDelay time.Duration
 }
 
+// ConsumerConfig configures one event consumer. Each field can be set with
+// CONSUMER_<NAME>_ENABLED, _EXCHANGE, _ROUTING_KEYS (comma separated), _QUEUE
+// and _WORKERS, e.g. CONSUMER_ORDER_WORKERS=10.
+type ConsumerConfig struct {
+	Enabled     bool
+	Exchange    string
+	RoutingKeys []string
+	Queue       string
+	WorkerCount int
+}
+
+// DispatchConfig bounds how a single notification is sent: at most
+// Parallelism channel and provider sends at once, all within Deadline
+type DispatchConfig struct {
+	Parallelism int
+	Deadline    time.Duration
+}
+
+type FanoutConfig struct {
+	BatchSize  int
+	QueueSize  int
+	Workers    int
+	BatchDelay time.Duration
+}
+
+// SchedulerConfig tunes dispatch of notifications scheduled with SendAt
+type SchedulerConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+}
+
+type FollowupConfig struct {
+	PollInterval             time.Duration
+	BatchSize                int
+	PaymentReminderIntervals []time.Duration
+	ReviewRequestDelay       time.Duration
+}
+
+type EmailConfig struct {
+	FromAddress string
+	BrandName   string
+	BaseURL     string
+}
+
+// SMTPConfig configures an SMTP relay for email. STARTTLS is used when the
+// server offers it; Username enables PLAIN auth.
+type SMTPConfig struct {
+	Name     string
+	Host     string
+	Port     string
+	Username string
+	Password string
+	Timeout  time.Duration
+}
+
+// SMSConfig configures the generic HTTP SMS gateway. BodyTemplate may use
+// {{to}} (E.164), {{to_digits}} (E.164 without "+"), {{message}} and
+// {{sender_id}}; values are escaped for ContentType.
+type SMSConfig struct {
+	Name         string
+	GatewayURL   string
+	Method       string
+	ContentType  string
+	BodyTemplate string
+	AuthHeader   string
+	AuthValue    string
+	Username     string
+	Password     string
+	SenderID     string
+	MaxSegments  int
+	Timeout      time.Duration
+}
+
+// FailoverConfig tunes provider failover chains: a provider is skipped for
+// Cooldown after FailureThreshold consecutive transient failures. Chains
+// lists, per channel, provider names in order of preference.
+type FailoverConfig struct {
+	FailureThreshold int
+	Cooldown         time.Duration
+	Chains           map[string][]string
+}
+
+// SenderGuardConfig limits every provider a sender talks to. RateLimit is
+// sends per second (0 for unlimited), overridden per provider name by
+// RateLimits. The circuit opens for BreakerOpenFor after BreakerThreshold
+// failures within BreakerWindow.
+type SenderGuardConfig struct {
+	RateLimit        int
+	RateLimits       map[string]int
+	Burst            int
+	Timeout          time.Duration
+	BreakerThreshold int
+	BreakerWindow    time.Duration
+	BreakerOpenFor   time.Duration
+}
+
+// WhatsAppConfig configures the WhatsApp Cloud API. BaseURL can point at a
+// local fake.
+type WhatsAppConfig struct {
+	BaseURL       string
+	PhoneNumberID string
+	AccessToken   string
+	Timeout       time.Duration
+}
+
+// WebhookConfig tunes delivery to webhook subscribers
+type WebhookConfig struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	DisableAfter   int
+	Timeout        time.Duration
+}
+
+// OpsConfig configures the internal chats that ops alerts are posted to.
+// TelegramBaseURL can point at a local fake.
+type OpsConfig struct {
+	SlackWebhookURL  string
+	TelegramBaseURL  string
+	TelegramBotToken string
+	TelegramChatID   string
+	Timeout          time.Duration
+}
+
+// WebPushConfig configures browser push. The VAPID keys are base64url, as
+// generated by common web-push tooling; the public key is what the
+// storefront subscribes with.
+type WebPushConfig struct {
+	VAPIDPublicKey  string
+	VAPIDPrivateKey string
+	Subject         string
+	TTL             time.Duration
+	Timeout         time.Duration
+}
+
+// APNsConfig configures direct iOS push with a .p8 signing key. Topic is the
+// app's bundle ID; BaseURL is the production or sandbox host, or a local
+// HTTP/2 stub.
+type APNsConfig struct {
+	BaseURL string
+	KeyFile string
+	KeyID   string
+	TeamID  string
+	Topic   string
+	Timeout time.Duration
+}
+
 func LoadConfig() (*AppConfig, error) {
-	return &AppConfig{
+	cfg := &AppConfig{
 		Env:        getEnv("ENV", "development"),
 		ServerPort: getEnv("SERVER_PORT", "8080"),
 		LogLevel:   getEnv("LOG_LEVEL", "info"),
@@ -53,8 +226,211 @@ func LoadConfig() (*AppConfig, error) {
 			PrefetchCount:  getEnvInt("RABBITMQ_PREFETCH_COUNT", 10),
 			ReconnectDelay: time.Duration(getEnvInt("RABBITMQ_RECONNECT_DELAY", 10)) * time.Second,
 		},
-		MockMode: getEnvBool("MOCK_MODE", true),
-	}, nil
+		Email: EmailConfig{
+			FromAddress: getEnv("EMAIL_FROM", "TokoHobby <no-reply@tokohobby.com>"),
+			BrandName:   getEnv("EMAIL_BRAND_NAME", "TokoHobby"),
+			BaseURL:     getEnv("APP_BASE_URL", "https://tokohobby.com"),
+		},
+		SMTP:         loadSMTPConfig("SMTP", "smtp"),
+		SMTPFallback: loadSMTPConfig("SMTP_FALLBACK", "smtp_fallback"),
+		SMS:          loadSMSConfig("SMS", "sms_gateway"),
+		SMSFallback:  loadSMSConfig("SMS_FALLBACK", "sms_fallback"),
+		Failover: FailoverConfig{
+			FailureThreshold: getEnvInt("FAILOVER_FAILURE_THRESHOLD", 3),
+			Cooldown:         time.Duration(getEnvInt("FAILOVER_COOLDOWN", 30)) * time.Second,
+		},
+		SenderGuard: SenderGuardConfig{
+			RateLimit:        getEnvInt("SENDER_RATE_LIMIT", 0),
+			RateLimits:       getEnvIntMap("SENDER_RATE_LIMITS"),
+			Burst:            getEnvInt("SENDER_BURST", 10),
+			Timeout:          time.Duration(getEnvInt("SENDER_TIMEOUT", 15)) * time.Second,
+			BreakerThreshold: getEnvInt("BREAKER_FAILURE_THRESHOLD", 5),
+			BreakerWindow:    time.Duration(getEnvInt("BREAKER_WINDOW", 60)) * time.Second,
+			BreakerOpenFor:   time.Duration(getEnvInt("BREAKER_OPEN_DURATION", 120)) * time.Second,
+		},
+		WhatsApp: WhatsAppConfig{
+			BaseURL:       getEnv("WHATSAPP_BASE_URL", "https://graph.facebook.com/v21.0"),
+			PhoneNumberID: getEnv("WHATSAPP_PHONE_NUMBER_ID", ""),
+			AccessToken:   getEnv("WHATSAPP_ACCESS_TOKEN", ""),
+			Timeout:       time.Duration(getEnvInt("WHATSAPP_TIMEOUT", 10)) * time.Second,
+		},
+		Webhook: WebhookConfig{
+			MaxAttempts:    getEnvInt("WEBHOOK_MAX_ATTEMPTS", 4),
+			InitialBackoff: time.Duration(getEnvInt("WEBHOOK_INITIAL_BACKOFF_MS", 1000)) * time.Millisecond,
+			MaxBackoff:     time.Duration(getEnvInt("WEBHOOK_MAX_BACKOFF_MS", 30000)) * time.Millisecond,
+			DisableAfter:   getEnvInt("WEBHOOK_DISABLE_AFTER", 5),
+			Timeout:        time.Duration(getEnvInt("WEBHOOK_TIMEOUT", 10)) * time.Second,
+		},
+		Ops: OpsConfig{
+			SlackWebhookURL:  getEnv("OPS_SLACK_WEBHOOK_URL", ""),
+			TelegramBaseURL:  getEnv("OPS_TELEGRAM_BASE_URL", "https://api.telegram.org"),
+			TelegramBotToken: getEnv("OPS_TELEGRAM_BOT_TOKEN", ""),
+			TelegramChatID:   getEnv("OPS_TELEGRAM_CHAT_ID", ""),
+			Timeout:          time.Duration(getEnvInt("OPS_CHAT_TIMEOUT", 10)) * time.Second,
+		},
+		WebPush: WebPushConfig{
+			VAPIDPublicKey:  getEnv("VAPID_PUBLIC_KEY", ""),
+			VAPIDPrivateKey: getEnv("VAPID_PRIVATE_KEY", ""),
+			Subject:         getEnv("VAPID_SUBJECT", "mailto:support@tokohobby.com"),
+			TTL:             time.Duration(getEnvInt("WEBPUSH_TTL", 86400)) * time.Second,
+			Timeout:         time.Duration(getEnvInt("WEBPUSH_TIMEOUT", 10)) * time.Second,
+		},
+		APNs: APNsConfig{
+			BaseURL: getEnv("APNS_BASE_URL", "https://api.push.apple.com"),
+			KeyFile: getEnv("APNS_KEY_FILE", ""),
+			KeyID:   getEnv("APNS_KEY_ID", ""),
+			TeamID:  getEnv("APNS_TEAM_ID", ""),
+			Topic:   getEnv("APNS_TOPIC", ""),
+			Timeout: time.Duration(getEnvInt("APNS_TIMEOUT", 10)) * time.Second,
+		},
+		Consumers: map[string]ConsumerConfig{
+			"order": loadConsumerConfig("order", ConsumerConfig{
+				Enabled:     true,
+				Exchange:    "order.events",
+				RoutingKeys: []string{"order.#"},
+				Queue:       "notifications.order.events",
+				WorkerCount: 5,
+			}),
+			"blog": loadConsumerConfig("blog", ConsumerConfig{
+				Enabled:     true,
+				Exchange:    "blog.events",
+				RoutingKeys: []string{"blog.#"},
+				Queue:       "blog.notifications",
+				WorkerCount: 5,
+			}),
+			"product": loadConsumerConfig("product", ConsumerConfig{
+				Enabled:     true,
+				Exchange:    "product.events",
+				RoutingKeys: []string{"product.#"},
+				Queue:       "notifications.product.events",
+				WorkerCount: 3,
+			}),
+			"shipment": loadConsumerConfig("shipment", ConsumerConfig{
+				Enabled:     true,
+				Exchange:    "shipment.events",
+				RoutingKeys: []string{"shipment.#"},
+				Queue:       "notifications.shipment.events",
+				WorkerCount: 3,
+			}),
+			"account": loadConsumerConfig("account", ConsumerConfig{
+				Enabled:     true,
+				Exchange:    "account.events",
+				RoutingKeys: []string{"account.#"},
+				Queue:       "notifications.account.events",
+				WorkerCount: 3,
+			}),
+			"whatsapp": loadConsumerConfig("whatsapp", ConsumerConfig{
+				Enabled:     true,
+				Exchange:    "whatsapp.events",
+				RoutingKeys: []string{"whatsapp.#"},
+				Queue:       "notifications.whatsapp.events",
+				WorkerCount: 2,
+			}),
+			"webhook": loadConsumerConfig("webhook", ConsumerConfig{
+				Enabled:     true,
+				Exchange:    "webhook.events",
+				RoutingKeys: []string{"webhook.#"},
+				Queue:       "notifications.webhook.events",
+				WorkerCount: 2,
+			}),
+			"device": loadConsumerConfig("device", ConsumerConfig{
+				Enabled:     true,
+				Exchange:    "device.events",
+				RoutingKeys: []string{"device.#"},
+				Queue:       "notifications.device.events",
+				WorkerCount: 2,
+			}),
+		},
+		Fanout: FanoutConfig{
+			BatchSize:  getEnvInt("FANOUT_BATCH_SIZE", 500),
+			QueueSize:  getEnvInt("FANOUT_QUEUE_SIZE", 100),
+			Workers:    getEnvInt("FANOUT_WORKERS", 2),
+			BatchDelay: time.Duration(getEnvInt("FANOUT_BATCH_DELAY_MS", 200)) * time.Millisecond,
+		},
+		Dispatch: DispatchConfig{
+			Parallelism: getEnvInt("DISPATCH_PARALLELISM", 4),
+			Deadline:    time.Duration(getEnvInt("NOTIFICATION_DEADLINE", 30)) * time.Second,
+		},
+		Followups: FollowupConfig{
+			PollInterval:             time.Duration(getEnvInt("FOLLOWUP_POLL_INTERVAL", 30)) * time.Second,
+			BatchSize:                getEnvInt("FOLLOWUP_BATCH_SIZE", 100),
+			PaymentReminderIntervals: getEnvDurations("PAYMENT_REMINDER_INTERVALS", []time.Duration{time.Hour, 12 * time.Hour}),
+			ReviewRequestDelay:       time.Duration(getEnvInt("REVIEW_REQUEST_DELAY_DAYS", 3)) * 24 * time.Hour,
+		},
+		Scheduler: SchedulerConfig{
+			PollInterval: time.Duration(getEnvInt("SCHEDULER_POLL_INTERVAL", 15)) * time.Second,
+			BatchSize:    getEnvInt("SCHEDULER_BATCH_SIZE", 100),
+		},
+		RoutingRulesFile: getEnv("ROUTING_RULES_FILE", ""),
+		MockMode:         getEnvBool("MOCK_MODE", true),
+
+		EnforcePreferences: getEnvBool("ENFORCE_PREFERENCES", false),
+		QuietHoursTimezone: getEnv("QUIET_HOURS_TIMEZONE", "Asia/Jakarta"),
+	}
+
+	// Providers are chained under their configured names, so the defaults
+	// follow any renamed gateway
+	cfg.Failover.Chains = map[string][]string{
+		"email": getEnvList("FAILOVER_EMAIL_PROVIDERS", []string{cfg.SMTP.Name, cfg.SMTPFallback.Name}),
+		"sms":   getEnvList("FAILOVER_SMS_PROVIDERS", []string{cfg.SMS.Name, cfg.SMSFallback.Name}),
+	}
+
+	if cfg.Webhook.MaxAttempts < 1 {
+		return nil, fmt.Errorf("WEBHOOK_MAX_ATTEMPTS must be at least 1, got %d", cfg.Webhook.MaxAttempts)
+	}
+	if cfg.Fanout.BatchSize < 1 {
+		return nil, fmt.Errorf("FANOUT_BATCH_SIZE must be at least 1, got %d", cfg.Fanout.BatchSize)
+	}
+	if cfg.Fanout.Workers < 1 {
+		return nil, fmt.Errorf("FANOUT_WORKERS must be at least 1, got %d", cfg.Fanout.Workers)
+	}
+
+	return cfg, nil
+}
+
+// loadSMTPConfig reads an SMTP relay from <prefix>_HOST and friends, so a
+// fallback relay can be configured next to the primary one
+func loadSMTPConfig(prefix, name string) SMTPConfig {
+	return SMTPConfig{
+		Name:     getEnv(prefix+"_NAME", name),
+		Host:     getEnv(prefix+"_HOST", ""),
+		Port:     getEnv(prefix+"_PORT", "587"),
+		Username: getEnv(prefix+"_USERNAME", ""),
+		Password: getEnv(prefix+"_PASSWORD", ""),
+		Timeout:  time.Duration(getEnvInt(prefix+"_TIMEOUT", 10)) * time.Second,
+	}
+}
+
+// loadSMSConfig reads an SMS gateway from <prefix>_GATEWAY_URL and friends,
+// so a fallback gateway can be configured next to the primary one
+func loadSMSConfig(prefix, name string) SMSConfig {
+	return SMSConfig{
+		Name:         getEnv(prefix+"_GATEWAY_NAME", name),
+		GatewayURL:   getEnv(prefix+"_GATEWAY_URL", ""),
+		Method:       getEnv(prefix+"_GATEWAY_METHOD", "POST"),
+		ContentType:  getEnv(prefix+"_GATEWAY_CONTENT_TYPE", "application/json"),
+		BodyTemplate: getEnv(prefix+"_GATEWAY_BODY_TEMPLATE", `{"to":"{{to}}","from":"{{sender_id}}","message":"{{message}}"}`),
+		AuthHeader:   getEnv(prefix+"_GATEWAY_AUTH_HEADER", "Authorization"),
+		AuthValue:    getEnv(prefix+"_GATEWAY_AUTH_VALUE", ""),
+		Username:     getEnv(prefix+"_GATEWAY_USERNAME", ""),
+		Password:     getEnv(prefix+"_GATEWAY_PASSWORD", ""),
+		SenderID:     getEnv(prefix+"_SENDER_ID", "TokoHobby"),
+		MaxSegments:  getEnvInt(prefix+"_MAX_SEGMENTS", 3),
+		Timeout:      time.Duration(getEnvInt(prefix+"_GATEWAY_TIMEOUT", 10)) * time.Second,
+	}
+}
+
+// loadConsumerConfig applies CONSUMER_<NAME>_* overrides to the defaults
+func loadConsumerConfig(name string, defaults ConsumerConfig) ConsumerConfig {
+	prefix := "CONSUMER_" + strings.ToUpper(name) + "_"
+
+	return ConsumerConfig{
+		Enabled:     getEnvBool(prefix+"ENABLED", defaults.Enabled),
+		Exchange:    getEnv(prefix+"EXCHANGE", defaults.Exchange),
+		RoutingKeys: getEnvList(prefix+"ROUTING_KEYS", defaults.RoutingKeys),
+		Queue:       getEnv(prefix+"QUEUE", defaults.Queue),
+		WorkerCount: getEnvInt(prefix+"WORKERS", defaults.WorkerCount),
+	}
 }
 
 func (c *DatabaseConfig) DSN() string {
@@ -86,3 +462,51 @@ func getEnvBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvIntMap parses a comma separated list such as "sms_gateway=5,apns=100"
+func getEnvIntMap(key string) map[string]int {
+	values := make(map[string]int)
+	for _, item := range getEnvList(key, nil) {
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		if intValue, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+			values[strings.TrimSpace(name)] = intValue
+		}
+	}
+	return values
+}
+
+// getEnvDurations parses a comma separated list such as "1h,12h"
+func getEnvDurations(key string, defaultValue []time.Duration) []time.Duration {
+	items := getEnvList(key, nil)
+	if items == nil {
+		return defaultValue
+	}
+
+	durations := make([]time.Duration, 0, len(items))
+	for _, item := range items {
+		d, err := time.ParseDuration(item)
+		if err != nil {
+			return defaultValue
+		}
+		durations = append(durations, d)
+	}
+	return durations
+}
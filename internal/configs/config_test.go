@@ -0,0 +1,83 @@
+package configs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetEnvDurations(t *testing.T) {
+	defaults := []time.Duration{time.Hour, 12 * time.Hour}
+
+	tests := []struct {
+		name  string
+		value string
+		want  []time.Duration
+	}{
+		{
+			name: "unset",
+			want: defaults,
+		},
+		{
+			name:  "list",
+			value: "30m, 6h,24h",
+			want:  []time.Duration{30 * time.Minute, 6 * time.Hour, 24 * time.Hour},
+		},
+		{
+			name:  "invalid item",
+			value: "1h,tomorrow",
+			want:  defaults,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PAYMENT_REMINDER_INTERVALS", tt.value)
+
+			got := getEnvDurations("PAYMENT_REMINDER_INTERVALS", defaults)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getEnvDurations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+		},
+		{
+			name:    "no webhook attempts",
+			env:     map[string]string{"WEBHOOK_MAX_ATTEMPTS": "0"},
+			wantErr: true,
+		},
+		{
+			name:    "empty fan-out batches",
+			env:     map[string]string{"FANOUT_BATCH_SIZE": "0"},
+			wantErr: true,
+		},
+		{
+			name:    "no fan-out workers",
+			env:     map[string]string{"FANOUT_WORKERS": "0"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			_, err := LoadConfig()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Device token platforms
+const (
+	PlatformFCM  = "fcm"
+	PlatformAPNs = "apns"
+	PlatformWeb  = "web"
+)
+
+// DeviceToken is a push target of one of a user's devices. For the web
+// platform Token is the subscription endpoint and P256dh/Auth its keys.
+type DeviceToken struct {
+	ID       uuid.UUID `json:"id"`
+	UserID   uuid.UUID `json:"user_id"`
+	Platform string    `json:"platform"`
+	Token    string    `json:"token"`
+	P256dh   string    `json:"p256dh,omitempty"`
+	Auth     string    `json:"-"`
+
+	Active     bool       `json:"active"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
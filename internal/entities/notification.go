@@ -15,8 +15,17 @@ type Notification struct {
 	Message  string                 `json:"message"`
 	Metadata map[string]interface{} `json:"metadata"`
 	Channels []string               `json:"channels"`
+	Priority string                 `json:"priority"`
 	Status   string                 `json:"status"`
 
+	// CollapseKey makes a notification replace the previous one with the
+	// same key for the user instead of stacking
+	CollapseKey string `json:"collapse_key,omitempty"`
+
+	// EventTime is when the event behind the notification happened. A
+	// collapsing notification never replaces one with a later EventTime.
+	EventTime *time.Time `json:"event_time,omitempty"`
+
 	IsRead bool       `json:"is_read"`
 	ReadAt *time.Time `json:"read_at,omitempty"`
 
@@ -31,6 +40,18 @@ type Notification struct {
 	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
+// NotificationDelivery records which provider delivered a notification on a
+// channel
+type NotificationDelivery struct {
+	ID             uuid.UUID `json:"id"`
+	NotificationID uuid.UUID `json:"notification_id"`
+	Channel        string    `json:"channel"`
+	Provider       string    `json:"provider"`
+	Status         string    `json:"status"`
+	Error          string    `json:"error,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
 type NotificationPreference struct {
 	ID     uuid.UUID `json:"id"`
 	UserID uuid.UUID `json:"user_id"`
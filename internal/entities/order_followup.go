@@ -0,0 +1,28 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OrderFollowup is a delayed action for an order that is cancelled when the
+// order moves on, e.g. a payment reminder cancelled by payment. Step numbers
+// the follow-ups of one kind for an order, e.g. the first and second payment
+// reminder; unlike DueAt, which retries move, it never changes.
+type OrderFollowup struct {
+	ID      uuid.UUID              `json:"id"`
+	OrderID string                 `json:"order_id"`
+	UserID  uuid.UUID              `json:"user_id"`
+	Kind    string                 `json:"kind"`
+	Step    int                    `json:"step"`
+	DueAt   time.Time              `json:"due_at"`
+	Payload map[string]interface{} `json:"payload"`
+	Status  string                 `json:"status"`
+
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
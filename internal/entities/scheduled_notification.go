@@ -0,0 +1,23 @@
+package entities
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ScheduledNotification is a notification request persisted until SendAt
+type ScheduledNotification struct {
+	ID      uuid.UUID       `json:"id"`
+	UserID  uuid.UUID       `json:"user_id"`
+	SendAt  time.Time       `json:"send_at"`
+	Request json.RawMessage `json:"request"`
+	Status  string          `json:"status"`
+
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,13 @@
+package messaging
+
+import "github.com/RehanAthallahAzhar/tokohobby-notifications/internal/routing"
+
+// AccountConsumerSpec returns the account/security consumer. Its rules use
+// the critical priority, so these notifications are sent regardless of user
+// preferences and quiet hours.
+func AccountConsumerSpec(engine *routing.Engine) ConsumerSpec {
+	return ConsumerSpec{
+		Name:  "account",
+		Rules: engine,
+	}
+}
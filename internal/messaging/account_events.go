@@ -0,0 +1,39 @@
+package messaging
+
+import "time"
+
+// PasswordChangedEvent is published after a user changes or resets their password
+type PasswordChangedEvent struct {
+	UserID    string    `json:"user_id"`
+	IPAddress string    `json:"ip_address"`
+	Device    string    `json:"device"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// NewDeviceLoginEvent is published when a user signs in from an unknown device
+type NewDeviceLoginEvent struct {
+	UserID     string    `json:"user_id"`
+	Device     string    `json:"device"`
+	IPAddress  string    `json:"ip_address"`
+	Location   string    `json:"location"`
+	LoggedInAt time.Time `json:"logged_in_at"`
+}
+
+// EmailChangedEvent is published when the account email address changes
+type EmailChangedEvent struct {
+	UserID    string    `json:"user_id"`
+	OldEmail  string    `json:"old_email"`
+	NewEmail  string    `json:"new_email"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// OTPRequestedEvent carries a one-time code for login, verification or
+// password reset
+type OTPRequestedEvent struct {
+	UserID           string    `json:"user_id"`
+	Code             string    `json:"code"`
+	Purpose          string    `json:"purpose"`
+	ExpiresInMinutes int       `json:"expires_in_minutes"`
+	PhoneNumber      string    `json:"phone_number,omitempty"`
+	RequestedAt      time.Time `json:"requested_at"`
+}
@@ -3,128 +3,230 @@ package messaging
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
-	messaging "github.com/RehanAthallahAzhar/tokohobby-messaging/rabbitmq"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/repositories"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/routing"
 	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/services"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/templates"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
-type BlogEventConsumer struct {
-	rmq          *messaging.RabbitMQ
+// BlogEventHandler handles blog, comment and follow events
+type BlogEventHandler struct {
 	notifService *services.NotificationService
+	fanout       *services.FanoutService
+	followers    *repositories.FollowerRepository
 	log          *logrus.Logger
 }
 
-func NewBlogEventConsumer(
-	rmq *messaging.RabbitMQ,
+func NewBlogEventHandler(
 	notifService *services.NotificationService,
+	fanout *services.FanoutService,
+	followers *repositories.FollowerRepository,
 	log *logrus.Logger,
-) *BlogEventConsumer {
-	return &BlogEventConsumer{
-		rmq:          rmq,
+) *BlogEventHandler {
+	return &BlogEventHandler{
 		notifService: notifService,
+		fanout:       fanout,
+		followers:    followers,
 		log:          log,
 	}
 }
 
-func (c *BlogEventConsumer) Start(ctx context.Context) error {
-	handler := func(ctx context.Context, body []byte) error {
-		var eventType struct {
-			Type string `json:"type"`
-		}
-
-		if err := json.Unmarshal(body, &eventType); err != nil {
-			return fmt.Errorf("failed to unmarshal event type: %w", err)
-		}
+// Spec returns the blog consumer; event types without a Go handler fall
+// back to the routing rules
+func (h *BlogEventHandler) Spec(engine *routing.Engine) ConsumerSpec {
+	return ConsumerSpec{
+		Name: "blog",
+		Handlers: map[string]EventHandler{
+			"blog.published":         h.handleBlogPublished,
+			"blog.author.followed":   h.handleAuthorFollowed,
+			"blog.author.unfollowed": h.handleAuthorUnfollowed,
+			"comment.added":          h.handleCommentAdded,
+			"comment.replied":        h.handleCommentAdded,
+		},
+		Rules: engine,
+	}
+}
 
-		switch eventType.Type {
-		case "blog.published":
-			return c.handleBlogPublished(ctx, body)
-		case "comment.added":
-			return c.handleCommentAdded(ctx, body)
-		default:
-			c.log.Warnf("Unknown event type: %s", eventType.Type)
-			return nil
-		}
+func (h *BlogEventHandler) handleBlogPublished(ctx context.Context, body []byte) error {
+	var event BlogPublishedEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal BlogPublishedEvent: %w", err)
 	}
 
-	opts := messaging.ConsumerOptions{
-		QueueName:   "blog.notifications",
-		WorkerCount: 5,
-		AutoAck:     false,
+	h.log.WithFields(logrus.Fields{
+		"blog_id": event.BlogID,
+		"author":  event.AuthorName,
+		"title":   event.Title,
+	}).Info("Processing blog published event")
+
+	authorID, err := uuid.Parse(event.AuthorID)
+	if err != nil {
+		return fmt.Errorf("invalid author_id %q: %w", event.AuthorID, err)
 	}
 
-	consumer := messaging.NewConsumer(c.rmq, opts, handler)
+	// Followers are notified in batches on the fan-out workers so authors with
+	// huge followings don't block this consumer
+	return h.fanout.Enqueue(services.FanoutJob{
+		Name: fmt.Sprintf("blog.published:%s", event.BlogID),
+		Recipients: func(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+			return h.followers.ListFollowers(ctx, authorID, after, limit)
+		},
+		Build: func(followerID uuid.UUID) *services.CreateNotificationRequest {
+			return &services.CreateNotificationRequest{
+				UserID:   followerID.String(),
+				Type:     "blog",
+				Category: "new_post",
+				Template: TemplateBlogPublished,
+				Channels: []string{"push", "in_app"},
+				Metadata: map[string]interface{}{
+					"blog_id":     event.BlogID,
+					"author_id":   event.AuthorID,
+					"author_name": event.AuthorName,
+					"title":       event.Title,
+					"excerpt":     event.Excerpt,
+				},
+				Action: &templates.EmailAction{
+					Label: "Read Blog",
+					URL:   fmt.Sprintf("/blogs/%s", event.BlogID),
+				},
+			}
+		},
+	})
+}
 
-	// Declare queue
-	if err := consumer.DeclareQueue(true, false); err != nil {
-		return fmt.Errorf("failed to declare queue: %w", err)
+func (h *BlogEventHandler) handleAuthorFollowed(ctx context.Context, body []byte) error {
+	var event AuthorFollowedEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal AuthorFollowedEvent: %w", err)
 	}
 
-	// Bind to blog.events with wildcard
-	if err := consumer.BindQueue("blog.events", "blog.#"); err != nil {
-		return fmt.Errorf("failed to bind queue: %w", err)
+	authorID, followerID, err := parseFollowIDs(event.AuthorID, event.FollowerID)
+	if err != nil {
+		return err
 	}
 
-	c.log.Info("Blog event consumer configured, starting to consume...")
+	h.log.WithFields(logrus.Fields{
+		"author_id":   event.AuthorID,
+		"follower_id": event.FollowerID,
+	}).Info("Processing author followed event")
 
-	return consumer.Start(ctx)
+	return h.followers.Follow(ctx, authorID, followerID)
 }
 
-func (c *BlogEventConsumer) handleBlogPublished(ctx context.Context, body []byte) error {
-	var event BlogPublishedEvent
+func (h *BlogEventHandler) handleAuthorUnfollowed(ctx context.Context, body []byte) error {
+	var event AuthorUnfollowedEvent
 	if err := json.Unmarshal(body, &event); err != nil {
-		return fmt.Errorf("failed to unmarshal BlogPublishedEvent: %w", err)
+		return fmt.Errorf("failed to unmarshal AuthorUnfollowedEvent: %w", err)
 	}
 
-	c.log.WithFields(logrus.Fields{
-		"blog_id": event.BlogID,
-		"author":  event.AuthorName,
-		"title":   event.Title,
-	}).Info("Processing blog published event")
+	authorID, followerID, err := parseFollowIDs(event.AuthorID, event.FollowerID)
+	if err != nil {
+		return err
+	}
 
-	// TODO: Get followers from database and send notifications
-	// For now, just log the event
-	message := fmt.Sprintf("%s published a new blog: %s", event.AuthorName, event.Title)
-	c.log.Infof("Would notify followers: %s - %s", message, event.Excerpt)
+	h.log.WithFields(logrus.Fields{
+		"author_id":   event.AuthorID,
+		"follower_id": event.FollowerID,
+	}).Info("Processing author unfollowed event")
 
-	// When follower system is implemented, use this:
-	// return c.notifService.SendBlogPublishedNotification(ctx, &services.BlogNotificationRequest{
-	//     FollowerIDs: followerIDs,
-	//     Title:       "New Blog Published",
-	//     Message:     message,
-	//     BlogURL:     fmt.Sprintf("/blogs/%s", event.BlogID),
-	// })
+	return h.followers.Unfollow(ctx, authorID, followerID)
+}
 
-	return nil
+func parseFollowIDs(authorID, followerID string) (uuid.UUID, uuid.UUID, error) {
+	author, err := uuid.Parse(authorID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid author_id %q: %w", authorID, err)
+	}
+	follower, err := uuid.Parse(followerID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid follower_id %q: %w", followerID, err)
+	}
+	return author, follower, nil
 }
 
-func (c *BlogEventConsumer) handleCommentAdded(ctx context.Context, body []byte) error {
+// handleCommentAdded handles both comment.added and comment.replied. Each
+// affected user gets at most one notification, picked in order of relevance:
+// the parent commenter (reply), the blog owner (comment), then anyone
+// @mentioned. The commenter is never notified about their own comment.
+func (h *BlogEventHandler) handleCommentAdded(ctx context.Context, body []byte) error {
 	var event CommentAddedEvent
 	if err := json.Unmarshal(body, &event); err != nil {
 		return fmt.Errorf("failed to unmarshal CommentAddedEvent: %w", err)
 	}
 
-	c.log.WithFields(logrus.Fields{
-		"comment_id": event.CommentID,
-		"blog_title": event.BlogTitle,
-		"commenter":  event.Commenter,
-		"blog_owner": event.BlogOwnerID,
-	}).Info("Processing comment added event")
+	h.log.WithFields(logrus.Fields{
+		"comment_id":        event.CommentID,
+		"parent_comment_id": event.ParentCommentID,
+		"blog_title":        event.BlogTitle,
+		"commenter":         event.Commenter,
+		"blog_owner":        event.BlogOwnerID,
+	}).Info("Processing comment event")
+
+	notified := map[string]bool{event.CommenterID: true}
+	var errs []error
+
+	notify := func(userID, category, template string) {
+		if userID == "" || notified[userID] {
+			return
+		}
+		notified[userID] = true
 
-	// Notify blog owner about new comment
-	return c.notifService.CreateAndSendNotification(ctx, &services.CreateNotificationRequest{
-		UserID:   event.BlogOwnerID,
+		if err := h.notifService.CreateAndSendNotification(ctx, commentNotification(&event, userID, category, template)); err != nil {
+			errs = append(errs, fmt.Errorf("%s notification for %s: %w", category, userID, err))
+		}
+	}
+
+	notify(event.ParentCommenterID, "reply", TemplateCommentReplied)
+	notify(event.BlogOwnerID, "comment", TemplateCommentAdded)
+
+	for _, username := range ParseMentions(event.Comment) {
+		userID, ok := event.mentionedUserID(username)
+		if !ok {
+			h.log.WithFields(logrus.Fields{
+				"comment_id": event.CommentID,
+				"username":   username,
+			}).Debug("Mentioned user not resolved, skipping")
+			continue
+		}
+		notify(userID, "mention", TemplateCommentMentioned)
+	}
+
+	return errors.Join(errs...)
+}
+
+func commentNotification(event *CommentAddedEvent, userID, category, template string) *services.CreateNotificationRequest {
+	threadID := event.ParentCommentID
+	if threadID == "" {
+		threadID = event.CommentID
+	}
+	deepLink := fmt.Sprintf("/blogs/%s?comment=%s#comment-%s", event.BlogID, event.CommentID, event.CommentID)
+
+	return &services.CreateNotificationRequest{
+		UserID:   userID,
 		Type:     "blog",
-		Category: "comment",
-		Title:    "New Comment",
-		Message:  fmt.Sprintf("%s commented on your blog '%s': %s", event.Commenter, event.BlogTitle, event.Comment),
+		Category: category,
+		Template: template,
 		Channels: []string{"email", "push", "in_app"},
+		Action: &templates.EmailAction{
+			Label: "View Comment",
+			URL:   deepLink,
+		},
 		Metadata: map[string]interface{}{
-			"blog_id":    event.BlogID,
-			"comment_id": event.CommentID,
-			"commenter":  event.Commenter,
+			"blog_id":           event.BlogID,
+			"comment_id":        event.CommentID,
+			"parent_comment_id": event.ParentCommentID,
+			"thread_id":         threadID,
+			"deep_link":         deepLink,
+			"commenter":         event.Commenter,
+			"commenter_id":      event.CommenterID,
+			"commenter_name":    event.Commenter,
+			"blog_title":        event.BlogTitle,
+			"comment":           event.Comment,
 		},
-	})
+	}
 }
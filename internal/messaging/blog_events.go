@@ -1,6 +1,10 @@
 package messaging
 
-import "time"
+import (
+	"regexp"
+	"strings"
+	"time"
+)
 
 // BlogPublishedEvent for notifying followers when blog is published
 type BlogPublishedEvent struct {
@@ -12,14 +16,66 @@ type BlogPublishedEvent struct {
 	PublishedAt time.Time `json:"published_at"`
 }
 
-// CommentAddedEvent for notifying blog owner when comment is added
+// CommentAddedEvent for notifying blog owner when comment is added.
+// It is also the payload of comment.replied, where the parent fields are set.
 type CommentAddedEvent struct {
-	CommentID   string    `json:"comment_id"`
-	BlogID      string    `json:"blog_id"`
-	BlogTitle   string    `json:"blog_title"`
-	CommenterID string    `json:"commenter_id"`
-	Commenter   string    `json:"commenter_name"`
-	Comment     string    `json:"comment"`
-	BlogOwnerID string    `json:"blog_owner_id"`
-	CreatedAt   time.Time `json:"created_at"`
+	CommentID         string    `json:"comment_id"`
+	ParentCommentID   string    `json:"parent_comment_id"`
+	ParentCommenterID string    `json:"parent_commenter_id"`
+	BlogID            string    `json:"blog_id"`
+	BlogTitle         string    `json:"blog_title"`
+	CommenterID       string    `json:"commenter_id"`
+	Commenter         string    `json:"commenter_name"`
+	Comment           string    `json:"comment"`
+	BlogOwnerID       string    `json:"blog_owner_id"`
+	CreatedAt         time.Time `json:"created_at"`
+
+	// MentionedUsers maps usernames mentioned in Comment to user IDs, as
+	// resolved by the blog service
+	MentionedUsers map[string]string `json:"mentioned_users"`
+}
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9_](?:[A-Za-z0-9_.]*[A-Za-z0-9_])?)`)
+
+// ParseMentions returns the distinct @usernames in text, in order of appearance
+func ParseMentions(text string) []string {
+	seen := make(map[string]bool)
+	var usernames []string
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		key := strings.ToLower(match[1])
+		if !seen[key] {
+			seen[key] = true
+			usernames = append(usernames, match[1])
+		}
+	}
+
+	return usernames
+}
+
+// mentionedUserID resolves a mentioned username, ignoring case
+func (e *CommentAddedEvent) mentionedUserID(username string) (string, bool) {
+	if id, ok := e.MentionedUsers[username]; ok {
+		return id, id != ""
+	}
+	for name, id := range e.MentionedUsers {
+		if strings.EqualFold(name, username) {
+			return id, id != ""
+		}
+	}
+	return "", false
+}
+
+// AuthorFollowedEvent for recording a new follower of a blog author
+type AuthorFollowedEvent struct {
+	AuthorID   string    `json:"author_id"`
+	FollowerID string    `json:"follower_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// AuthorUnfollowedEvent for removing a follower of a blog author
+type AuthorUnfollowedEvent struct {
+	AuthorID     string    `json:"author_id"`
+	FollowerID   string    `json:"follower_id"`
+	UnfollowedAt time.Time `json:"unfollowed_at"`
 }
@@ -0,0 +1,178 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	messaging "github.com/RehanAthallahAzhar/tokohobby-messaging/rabbitmq"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/configs"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/routing"
+	"github.com/sirupsen/logrus"
+)
+
+// EventHandler handles the raw body of a single event type
+type EventHandler func(ctx context.Context, body []byte) error
+
+// withRules runs hook and then the routing rules for eventType, so Go side
+// effects (such as scheduling follow-ups) can be combined with rule-driven
+// notifications for the same event. The rules only fail the event when they
+// delivered nothing, but the redelivered event runs hook again, so hooks
+// must be idempotent.
+func withRules(engine *routing.Engine, eventType string, hook EventHandler) EventHandler {
+	return func(ctx context.Context, body []byte) error {
+		if err := hook(ctx, body); err != nil {
+			return err
+		}
+		if engine != nil && engine.Handles(eventType) {
+			return engine.Dispatch(ctx, eventType, body)
+		}
+		return nil
+	}
+}
+
+// ConsumerSpec describes a queue consumer: where it binds, how many workers
+// it runs and which handler processes each event type. Domains only set the
+// name and handlers; the bindings come from configs.ConsumerConfig.
+type ConsumerSpec struct {
+	Name        string
+	Exchange    string
+	RoutingKeys []string
+	Queue       string
+	WorkerCount int
+
+	// Handlers maps the event "type" field to its handler
+	Handlers map[string]EventHandler
+
+	// Rules handles event types without a Go handler, if set
+	Rules *routing.Engine
+}
+
+// WithConfig returns the spec bound and sized according to cfg
+func (s ConsumerSpec) WithConfig(cfg configs.ConsumerConfig) ConsumerSpec {
+	s.Exchange = cfg.Exchange
+	s.RoutingKeys = cfg.RoutingKeys
+	s.Queue = cfg.Queue
+	s.WorkerCount = cfg.WorkerCount
+	return s
+}
+
+// ConsumerRegistry wires up every registered consumer on one connection
+type ConsumerRegistry struct {
+	rmq   *messaging.RabbitMQ
+	specs []ConsumerSpec
+	log   *logrus.Logger
+	wg    sync.WaitGroup
+}
+
+func NewConsumerRegistry(rmq *messaging.RabbitMQ, log *logrus.Logger) *ConsumerRegistry {
+	return &ConsumerRegistry{
+		rmq: rmq,
+		log: log,
+	}
+}
+
+// Register validates and adds a consumer spec
+func (r *ConsumerRegistry) Register(spec ConsumerSpec) error {
+	if spec.Name == "" {
+		return fmt.Errorf("consumer name is required")
+	}
+	for _, existing := range r.specs {
+		if existing.Name == spec.Name {
+			return fmt.Errorf("consumer %q already registered", spec.Name)
+		}
+		if existing.Queue == spec.Queue {
+			return fmt.Errorf("consumer %q: queue %q already used by %q", spec.Name, spec.Queue, existing.Name)
+		}
+	}
+	if spec.Queue == "" || spec.Exchange == "" || len(spec.RoutingKeys) == 0 {
+		return fmt.Errorf("consumer %q: queue, exchange and routing keys are required", spec.Name)
+	}
+	if spec.WorkerCount <= 0 {
+		return fmt.Errorf("consumer %q: worker count must be positive", spec.Name)
+	}
+	if len(spec.Handlers) == 0 && spec.Rules == nil {
+		return fmt.Errorf("consumer %q: no handlers or rules", spec.Name)
+	}
+
+	r.specs = append(r.specs, spec)
+	return nil
+}
+
+// Start launches every registered consumer in its own goroutine
+func (r *ConsumerRegistry) Start(ctx context.Context) {
+	for _, spec := range r.specs {
+		spec := spec
+
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+
+			if err := r.run(ctx, spec); err != nil {
+				r.log.WithError(err).WithField("consumer", spec.Name).Error("Consumer error")
+			}
+		}()
+	}
+}
+
+// Wait blocks until every consumer has stopped
+func (r *ConsumerRegistry) Wait() {
+	r.wg.Wait()
+}
+
+func (r *ConsumerRegistry) run(ctx context.Context, spec ConsumerSpec) error {
+	log := r.log.WithFields(logrus.Fields{
+		"consumer": spec.Name,
+		"queue":    spec.Queue,
+		"workers":  spec.WorkerCount,
+	})
+	log.Info("Starting event consumer...")
+
+	opts := messaging.ConsumerOptions{
+		QueueName:   spec.Queue,
+		WorkerCount: spec.WorkerCount,
+		AutoAck:     false,
+	}
+
+	consumer := messaging.NewConsumer(r.rmq, opts, r.handler(spec))
+
+	// Declare queue
+	if err := consumer.DeclareQueue(true, false); err != nil {
+		return fmt.Errorf("failed to declare queue: %w", err)
+	}
+
+	for _, key := range spec.RoutingKeys {
+		if err := consumer.BindQueue(spec.Exchange, key); err != nil {
+			return fmt.Errorf("failed to bind queue to %s (%s): %w", spec.Exchange, key, err)
+		}
+	}
+
+	log.Info("Event consumer configured, starting to consume...")
+
+	return consumer.Start(ctx)
+}
+
+// handler routes a message to the handler registered for its "type" field
+func (r *ConsumerRegistry) handler(spec ConsumerSpec) func(ctx context.Context, body []byte) error {
+	return func(ctx context.Context, body []byte) error {
+		var eventType struct {
+			Type string `json:"type"`
+		}
+
+		if err := json.Unmarshal(body, &eventType); err != nil {
+			return fmt.Errorf("failed to unmarshal event type: %w", err)
+		}
+
+		if handle, ok := spec.Handlers[eventType.Type]; ok {
+			return handle(ctx, body)
+		}
+
+		if spec.Rules != nil && spec.Rules.Handles(eventType.Type) {
+			return spec.Rules.Dispatch(ctx, eventType.Type, body)
+		}
+
+		r.log.WithField("consumer", spec.Name).Warnf("Unknown event type: %s", eventType.Type)
+		return nil
+	}
+}
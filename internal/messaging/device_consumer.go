@@ -0,0 +1,127 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/entities"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/repositories"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// DeviceRegisteredEvent registers a device for push. Native apps send their
+// FCM or APNs token; browsers send their PushSubscription as returned by
+// PushSubscription.toJSON() with platform "web".
+type DeviceRegisteredEvent struct {
+	UserID       string               `json:"user_id"`
+	Platform     string               `json:"platform"`
+	Token        string               `json:"token,omitempty"`
+	Subscription *WebPushSubscription `json:"subscription,omitempty"`
+}
+
+// DeviceUnregisteredEvent removes a device, e.g. on logout
+type DeviceUnregisteredEvent struct {
+	Platform     string               `json:"platform"`
+	Token        string               `json:"token,omitempty"`
+	Subscription *WebPushSubscription `json:"subscription,omitempty"`
+}
+
+// WebPushSubscription is a browser push subscription
+type WebPushSubscription struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+// DeviceEventHandler maintains users' device tokens
+type DeviceEventHandler struct {
+	devices *repositories.DeviceTokenRepository
+	log     *logrus.Logger
+}
+
+func NewDeviceEventHandler(devices *repositories.DeviceTokenRepository, log *logrus.Logger) *DeviceEventHandler {
+	return &DeviceEventHandler{
+		devices: devices,
+		log:     log,
+	}
+}
+
+// Spec returns the device registration consumer
+func (h *DeviceEventHandler) Spec() ConsumerSpec {
+	return ConsumerSpec{
+		Name: "device",
+		Handlers: map[string]EventHandler{
+			"device.registered":   h.handleRegistered,
+			"device.unregistered": h.handleUnregistered,
+		},
+	}
+}
+
+func (h *DeviceEventHandler) handleRegistered(ctx context.Context, body []byte) error {
+	var event DeviceRegisteredEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal DeviceRegisteredEvent: %w", err)
+	}
+
+	userID, err := uuid.Parse(event.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid user_id %q: %w", event.UserID, err)
+	}
+
+	token := &entities.DeviceToken{
+		UserID:   userID,
+		Platform: event.Platform,
+		Token:    event.Token,
+	}
+
+	switch event.Platform {
+	case entities.PlatformFCM, entities.PlatformAPNs:
+		if token.Token == "" {
+			return fmt.Errorf("%s device token is required", event.Platform)
+		}
+	case entities.PlatformWeb:
+		sub := event.Subscription
+		if sub == nil || sub.Keys.P256dh == "" || sub.Keys.Auth == "" {
+			return fmt.Errorf("web push subscription with p256dh and auth keys is required")
+		}
+		if u, err := url.Parse(sub.Endpoint); err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("invalid web push endpoint %q", sub.Endpoint)
+		}
+		token.Token = sub.Endpoint
+		token.P256dh = sub.Keys.P256dh
+		token.Auth = sub.Keys.Auth
+	default:
+		return fmt.Errorf("unknown device platform %q", event.Platform)
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"user_id":  event.UserID,
+		"platform": event.Platform,
+	}).Info("Registering device")
+
+	return h.devices.Register(ctx, token)
+}
+
+func (h *DeviceEventHandler) handleUnregistered(ctx context.Context, body []byte) error {
+	var event DeviceUnregisteredEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal DeviceUnregisteredEvent: %w", err)
+	}
+
+	token := event.Token
+	if event.Subscription != nil {
+		token = event.Subscription.Endpoint
+	}
+	if token == "" {
+		return fmt.Errorf("device token is required")
+	}
+
+	h.log.WithField("platform", event.Platform).Info("Removing device")
+
+	return h.devices.Unregister(ctx, event.Platform, token)
+}
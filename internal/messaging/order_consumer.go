@@ -4,303 +4,230 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
-	messaging "github.com/RehanAthallahAzhar/tokohobby-messaging/rabbitmq"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/entities"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/routing"
 	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/services"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/templates"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
-type OrderEventConsumer struct {
-	rmq          *messaging.RabbitMQ
-	notifService *services.NotificationService
-	log          *logrus.Logger
-}
+// Order follow-up kinds
+const (
+	FollowupPaymentReminder = "payment_reminder"
+	FollowupReviewRequest   = "review_request"
+)
 
-func NewOrderEventConsumer(rmq *messaging.RabbitMQ, notifService *services.NotificationService, log *logrus.Logger) *OrderEventConsumer {
-	return &OrderEventConsumer{
-		rmq:          rmq,
-		notifService: notifService,
-		log:          log,
-	}
+// OrderEventHandler handles order events. Notifications come from the
+// declarative rules in the routing engine (see default_rules.json); the Go
+// handlers only manage follow-ups such as payment reminders and review
+// requests.
+type OrderEventHandler struct {
+	notifService      *services.NotificationService
+	followups         *services.FollowupScheduler
+	reminderIntervals []time.Duration
+	reviewDelay       time.Duration
+	log               *logrus.Logger
 }
 
-func (c *OrderEventConsumer) Start(ctx context.Context) error {
-	c.log.Info("Starting Order Event Consumer...")
-
-	// Message handler
-	handler := func(ctx context.Context, body []byte) error {
-		// Try to determine event type from message
-		var eventType struct {
-			Type string `json:"type"`
-		}
-
-		if err := json.Unmarshal(body, &eventType); err != nil {
-			c.log.WithError(err).Error("Failed to parse event type")
-			return err
-		}
-
-		// Route to appropriate handler based on type
-		switch eventType.Type {
-		case "order.created":
-			return c.handleOrderCreated(ctx, body)
-		case "order.paid":
-			return c.handleOrderPaid(ctx, body)
-		case "order.shipped":
-			return c.handleOrderShipped(ctx, body)
-		case "order.delivered":
-			return c.handleOrderDelivered(ctx, body)
-		case "order.cancelled":
-			return c.handleOrderCancelled(ctx, body)
-		case "order.refunded":
-			return c.handleOrderRefunded(ctx, body)
-		case "order.status.changed":
-			return c.handleOrderStatusChanged(ctx, body)
-		default:
-			c.log.Warnf("Unknown event type: %s", eventType.Type)
-			return nil
-		}
-	}
-
-	// Create consumer
-	opts := messaging.ConsumerOptions{
-		QueueName:   "notifications.order.events",
-		WorkerCount: 5,
-		AutoAck:     false,
-	}
-
-	consumer := messaging.NewConsumer(c.rmq, opts, handler)
-
-	// Declare queue
-	if err := consumer.DeclareQueue(true, false); err != nil {
-		return fmt.Errorf("failed to declare queue: %w", err)
+func NewOrderEventHandler(
+	notifService *services.NotificationService,
+	followups *services.FollowupScheduler,
+	reminderIntervals []time.Duration,
+	reviewDelay time.Duration,
+	log *logrus.Logger,
+) *OrderEventHandler {
+	return &OrderEventHandler{
+		notifService:      notifService,
+		followups:         followups,
+		reminderIntervals: reminderIntervals,
+		reviewDelay:       reviewDelay,
+		log:               log,
 	}
+}
 
-	// Bind to order exchange with all order routing keys
-	if err := consumer.BindQueue("order.events", "order.#"); err != nil {
-		return fmt.Errorf("failed to bind queue: %w", err)
+// Spec returns the order consumer
+func (h *OrderEventHandler) Spec(engine *routing.Engine) ConsumerSpec {
+	return ConsumerSpec{
+		Name: "order",
+		Handlers: map[string]EventHandler{
+			"order.created":        withRules(engine, "order.created", h.schedulePaymentReminders),
+			"order.paid":           withRules(engine, "order.paid", h.cancelFollowups(FollowupPaymentReminder)),
+			"order.cancelled":      withRules(engine, "order.cancelled", h.cancelFollowups(FollowupPaymentReminder, FollowupReviewRequest)),
+			"order.delivered":      withRules(engine, "order.delivered", h.scheduleReviewRequest),
+			"order.refunded":       withRules(engine, "order.refunded", h.cancelFollowups(FollowupReviewRequest)),
+			"order.status.changed": withRules(engine, "order.status.changed", h.handleStatusChanged),
+		},
+		Rules: engine,
 	}
+}
 
-	c.log.Info("Order event consumer configured, starting to consume...")
-
-	// Start consuming
-	return consumer.Start(ctx)
+// RegisterFollowups registers the handlers of order follow-ups
+func (h *OrderEventHandler) RegisterFollowups() {
+	h.followups.Register(FollowupPaymentReminder, h.sendPaymentReminder)
+	h.followups.Register(FollowupReviewRequest, h.sendReviewRequest)
 }
 
-func (c *OrderEventConsumer) handleOrderCreated(ctx context.Context, body []byte) error {
+// schedulePaymentReminders schedules a reminder for every configured
+// interval after order creation, cancelled once the order is paid or cancelled
+func (h *OrderEventHandler) schedulePaymentReminders(ctx context.Context, body []byte) error {
 	var event OrderCreatedEvent
 	if err := json.Unmarshal(body, &event); err != nil {
 		return fmt.Errorf("failed to unmarshal OrderCreatedEvent: %w", err)
 	}
 
-	c.log.WithFields(logrus.Fields{
-		"order_id": event.OrderID,
-		"user_id":  event.UserID,
-		"amount":   event.TotalAmount,
-	}).Info("Processing OrderCreatedEvent")
-
-	// Create notification
-	return c.notifService.CreateAndSendNotification(ctx, &services.CreateNotificationRequest{
-		UserID:   event.UserID,
-		Type:     "order",
-		Category: "created",
-		Title:    "Pesanan Dikonfirmasi",
-		Message:  fmt.Sprintf("Pesanan #%s telah dikonfirmasi dengan total Rp %.0f", event.OrderID, event.TotalAmount),
-		Channels: []string{"email", "push", "in_app"},
-		Metadata: map[string]interface{}{
-			"order_id":     event.OrderID,
-			"total_amount": event.TotalAmount,
-			"item_count":   event.ItemCount,
-		},
-	})
-}
+	userID, err := uuid.Parse(event.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid user_id %q: %w", event.UserID, err)
+	}
 
-func (c *OrderEventConsumer) handleOrderStatusChanged(ctx context.Context, body []byte) error {
-	var event OrderStatusChangedEvent
-	if err := json.Unmarshal(body, &event); err != nil {
-		return fmt.Errorf("failed to unmarshal OrderStatusChangedEvent: %w", err)
+	payload, err := eventPayload(event)
+	if err != nil {
+		return err
 	}
 
-	c.log.WithFields(logrus.Fields{
-		"order_id": event.OrderID,
-		"user_id":  event.UserID,
-		"status":   event.Status,
-	}).Info("Processing OrderStatusChangedEvent")
+	createdAt := event.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
 
-	// Map status to notification message
-	title, message := c.getStatusMessage(event.Status, event.OrderID)
+	// Reminders are identified by their step, so a redelivered event does
+	// not schedule them again
+	for step, interval := range h.reminderIntervals {
+		err := h.followups.Schedule(ctx, &entities.OrderFollowup{
+			OrderID: event.OrderID,
+			UserID:  userID,
+			Kind:    FollowupPaymentReminder,
+			Step:    step,
+			DueAt:   createdAt.Add(interval),
+			Payload: payload,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to schedule payment reminder: %w", err)
+		}
+	}
 
-	return c.notifService.CreateAndSendNotification(ctx, &services.CreateNotificationRequest{
-		UserID:   event.UserID,
-		Type:     "order",
-		Category: "status_changed",
-		Title:    title,
-		Message:  message,
-		Channels: []string{"email", "push", "in_app"},
-		Metadata: map[string]interface{}{
-			"order_id": event.OrderID,
-			"status":   event.Status,
-		},
-	})
+	return nil
 }
 
-func (c *OrderEventConsumer) handleOrderShipped(ctx context.Context, body []byte) error {
-	var event OrderShippedEvent
+// scheduleReviewRequest asks for a review reviewDelay after delivery, unless
+// the order is refunded or cancelled first
+func (h *OrderEventHandler) scheduleReviewRequest(ctx context.Context, body []byte) error {
+	var event OrderDeliveredEvent
 	if err := json.Unmarshal(body, &event); err != nil {
-		return fmt.Errorf("failed to unmarshal OrderShippedEvent: %w", err)
+		return fmt.Errorf("failed to unmarshal OrderDeliveredEvent: %w", err)
 	}
 
-	c.log.WithFields(logrus.Fields{
-		"order_id":        event.OrderID,
-		"user_id":         event.UserID,
-		"tracking_number": event.TrackingNumber,
-	}).Info("Processing OrderShippedEvent")
-
-	return c.notifService.CreateAndSendNotification(ctx, &services.CreateNotificationRequest{
-		UserID:   event.UserID,
-		Type:     "order",
-		Category: "shipped",
-		Title:    "Pesanan Dikirim",
-		Message:  fmt.Sprintf("Pesanan #%s telah dikirim via %s. Nomor resi: %s", event.OrderID, event.Courier, event.TrackingNumber),
-		Channels: []string{"email", "push", "in_app"},
-		Metadata: map[string]interface{}{
-			"order_id":          event.OrderID,
-			"tracking_number":   event.TrackingNumber,
-			"courier":           event.Courier,
-			"estimated_arrival": event.EstimatedArrival,
-		},
-	})
-}
+	userID, err := uuid.Parse(event.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid user_id %q: %w", event.UserID, err)
+	}
 
-func (c *OrderEventConsumer) handleOrderPaid(ctx context.Context, body []byte) error {
-	var event OrderPaidEvent
-	if err := json.Unmarshal(body, &event); err != nil {
-		return fmt.Errorf("failed to unmarshal OrderPaidEvent: %w", err)
+	payload, err := eventPayload(event)
+	if err != nil {
+		return err
 	}
 
-	c.log.WithFields(logrus.Fields{
-		"order_id": event.OrderID,
-		"user_id":  event.UserID,
-		"amount":   event.PaidAmount,
-		"gateway":  event.PaymentGateway,
-	}).Info("Processing OrderPaidEvent")
+	deliveredAt := event.DeliveredAt
+	if deliveredAt.IsZero() {
+		deliveredAt = time.Now()
+	}
 
-	return c.notifService.CreateAndSendNotification(ctx, &services.CreateNotificationRequest{
-		UserID:   event.UserID,
-		Type:     "order",
-		Category: "paid",
-		Title:    "Pembayaran Berhasil",
-		Message:  fmt.Sprintf("Pembayaran pesanan #%s sebesar Rp %.0f telah dikonfirmasi via %s", event.OrderID, event.PaidAmount, event.PaymentGateway),
-		Channels: []string{"email", "push", "in_app"},
-		Metadata: map[string]interface{}{
-			"order_id":        event.OrderID,
-			"paid_amount":     event.PaidAmount,
-			"payment_gateway": event.PaymentGateway,
-			"transaction_id":  event.TransactionID,
-		},
+	err = h.followups.Schedule(ctx, &entities.OrderFollowup{
+		OrderID: event.OrderID,
+		UserID:  userID,
+		Kind:    FollowupReviewRequest,
+		DueAt:   deliveredAt.Add(h.reviewDelay),
+		Payload: payload,
 	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule review request: %w", err)
+	}
+	return nil
 }
 
-func (c *OrderEventConsumer) handleOrderDelivered(ctx context.Context, body []byte) error {
-	var event OrderDeliveredEvent
-	if err := json.Unmarshal(body, &event); err != nil {
-		return fmt.Errorf("failed to unmarshal OrderDeliveredEvent: %w", err)
-	}
+// cancelFollowups returns a hook cancelling the order's pending follow-ups
+// of the given kinds
+func (h *OrderEventHandler) cancelFollowups(kinds ...string) EventHandler {
+	return func(ctx context.Context, body []byte) error {
+		var event struct {
+			OrderID string `json:"order_id"`
+			UserID  string `json:"user_id"`
+		}
+		if err := json.Unmarshal(body, &event); err != nil {
+			return fmt.Errorf("failed to unmarshal order event: %w", err)
+		}
 
-	c.log.WithFields(logrus.Fields{
-		"order_id": event.OrderID,
-		"user_id":  event.UserID,
-		"receiver": event.ReceiverName,
-	}).Info("Processing OrderDeliveredEvent")
+		userID, err := uuid.Parse(event.UserID)
+		if err != nil {
+			return fmt.Errorf("invalid user_id %q: %w", event.UserID, err)
+		}
 
-	return c.notifService.CreateAndSendNotification(ctx, &services.CreateNotificationRequest{
-		UserID:   event.UserID,
-		Type:     "order",
-		Category: "delivered",
-		Title:    "Pesanan Telah Sampai",
-		Message:  fmt.Sprintf("Pesanan #%s telah diterima oleh %s", event.OrderID, event.ReceiverName),
-		Channels: []string{"email", "push", "in_app"},
-		Metadata: map[string]interface{}{
-			"order_id":       event.OrderID,
-			"receiver_name":  event.ReceiverName,
-			"delivery_proof": event.DeliveryProof,
-		},
-	})
+		return h.followups.Cancel(ctx, event.OrderID, userID, kinds...)
+	}
 }
 
-func (c *OrderEventConsumer) handleOrderCancelled(ctx context.Context, body []byte) error {
-	var event OrderCancelledEvent
+func (h *OrderEventHandler) handleStatusChanged(ctx context.Context, body []byte) error {
+	var event OrderStatusChangedEvent
 	if err := json.Unmarshal(body, &event); err != nil {
-		return fmt.Errorf("failed to unmarshal OrderCancelledEvent: %w", err)
+		return fmt.Errorf("failed to unmarshal OrderStatusChangedEvent: %w", err)
 	}
 
-	c.log.WithFields(logrus.Fields{
-		"order_id":     event.OrderID,
-		"user_id":      event.UserID,
-		"cancelled_by": event.CancelledBy,
-		"reason":       event.CancelReason,
-	}).Info("Processing OrderCancelledEvent")
+	switch event.Status {
+	case "pending":
+		return nil
+	case "cancelled", "refunded":
+		return h.cancelFollowups(FollowupPaymentReminder, FollowupReviewRequest)(ctx, body)
+	default:
+		// Any status past pending means there is nothing left to pay
+		return h.cancelFollowups(FollowupPaymentReminder)(ctx, body)
+	}
+}
 
-	return c.notifService.CreateAndSendNotification(ctx, &services.CreateNotificationRequest{
-		UserID:   event.UserID,
+func (h *OrderEventHandler) sendPaymentReminder(ctx context.Context, f entities.OrderFollowup) error {
+	return h.notifService.CreateAndSendNotification(ctx, &services.CreateNotificationRequest{
+		UserID:   f.UserID.String(),
 		Type:     "order",
-		Category: "cancelled",
-		Title:    "Pesanan Dibatalkan",
-		Message:  fmt.Sprintf("Pesanan #%s telah dibatalkan. Alasan: %s. Refund Rp %.0f akan diproses", event.OrderID, event.CancelReason, event.RefundAmount),
-		Channels: []string{"email", "push", "in_app"},
-		Metadata: map[string]interface{}{
-			"order_id":         event.OrderID,
-			"cancel_reason":    event.CancelReason,
-			"cancelled_by":     event.CancelledBy,
-			"refund_amount":    event.RefundAmount,
-			"cancellation_fee": event.CancellationFee,
+		Category: "payment_reminder",
+		Template: TemplatePaymentReminder,
+		Channels: []string{"email", "push", "whatsapp", "in_app"},
+		Priority: "high",
+		Metadata: f.Payload,
+		Action: &templates.EmailAction{
+			Label: "Bayar Sekarang",
+			URL:   fmt.Sprintf("/orders/%s/pay", f.OrderID),
 		},
 	})
 }
 
-func (c *OrderEventConsumer) handleOrderRefunded(ctx context.Context, body []byte) error {
-	var event OrderRefundedEvent
-	if err := json.Unmarshal(body, &event); err != nil {
-		return fmt.Errorf("failed to unmarshal OrderRefundedEvent: %w", err)
-	}
-
-	c.log.WithFields(logrus.Fields{
-		"order_id": event.OrderID,
-		"user_id":  event.UserID,
-		"amount":   event.RefundAmount,
-	}).Info("Processing OrderRefundedEvent")
-
-	return c.notifService.CreateAndSendNotification(ctx, &services.CreateNotificationRequest{
-		UserID:   event.UserID,
+func (h *OrderEventHandler) sendReviewRequest(ctx context.Context, f entities.OrderFollowup) error {
+	return h.notifService.CreateAndSendNotification(ctx, &services.CreateNotificationRequest{
+		UserID:   f.UserID.String(),
 		Type:     "order",
-		Category: "refunded",
-		Title:    "Refund Diproses",
-		Message:  fmt.Sprintf("Refund pesanan #%s sebesar Rp %.0f sedang diproses via %s", event.OrderID, event.RefundAmount, event.RefundMethod),
+		Category: "review_request",
+		Template: TemplateReviewRequest,
 		Channels: []string{"email", "push", "in_app"},
-		Metadata: map[string]interface{}{
-			"order_id":         event.OrderID,
-			"refund_amount":    event.RefundAmount,
-			"refund_method":    event.RefundMethod,
-			"refund_reference": event.RefundReference,
-			"expected_credit":  event.ExpectedCredit,
+		Priority: "low",
+		Metadata: f.Payload,
+		Action: &templates.EmailAction{
+			Label: "Tulis Ulasan",
+			URL:   fmt.Sprintf("/orders/%s/review", f.OrderID),
 		},
 	})
 }
 
-func (c *OrderEventConsumer) getStatusMessage(status, orderID string) (string, string) {
-	switch status {
-	case "pending":
-		return "Menunggu Pembayaran", fmt.Sprintf("Pesanan #%s menunggu pembayaran", orderID)
-	case "paid":
-		return "Pembayaran Diterima", fmt.Sprintf("Pembayaran pesanan #%s telah diterima", orderID)
-	case "processing":
-		return "Pesanan Diproses", fmt.Sprintf("Pesanan #%s sedang diproses", orderID)
-	case "shipped":
-		return "Pesanan Dikirim", fmt.Sprintf("Pesanan #%s sedang dalam pengiriman", orderID)
-	case "delivered":
-		return "Pesanan Sampai", fmt.Sprintf("Pesanan #%s telah sampai", orderID)
-	case "cancelled":
-		return "Pesanan Dibatalkan", fmt.Sprintf("Pesanan #%s telah dibatalkan", orderID)
-	default:
-		return "Status Pesanan Diubah", fmt.Sprintf("Status pesanan #%s: %s", orderID, status)
+// eventPayload converts an event to the map form used for template data
+func eventPayload(event interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal event: %w", err)
 	}
+	return payload, nil
 }
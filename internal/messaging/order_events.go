@@ -19,6 +19,9 @@ type OrderCreatedEvent struct {
 	TotalAmount   float64   `json:"total_amount"`
 	ItemCount     int       `json:"item_count"`
 	PaymentMethod string    `json:"payment_method"`
+	SellerIDs     []string  `json:"seller_ids,omitempty"`
+	Email         string    `json:"email,omitempty"`
+	PhoneNumber   string    `json:"phone_number,omitempty"`
 	CreatedAt     time.Time `json:"created_at"`
 }
 
@@ -29,6 +32,7 @@ type OrderShippedEvent struct {
 	TrackingNumber   string    `json:"tracking_number"`
 	Courier          string    `json:"courier"`
 	EstimatedArrival time.Time `json:"estimated_arrival"`
+	PhoneNumber      string    `json:"phone_number,omitempty"`
 	ShippedAt        time.Time `json:"shipped_at"`
 }
 
@@ -40,6 +44,7 @@ type OrderPaidEvent struct {
 	PaymentMethod  string    `json:"payment_method"`
 	PaymentGateway string    `json:"payment_gateway"`
 	TransactionID  string    `json:"transaction_id"`
+	SellerIDs      []string  `json:"seller_ids,omitempty"`
 	PaidAt         time.Time `json:"paid_at"`
 }
 
@@ -49,6 +54,7 @@ type OrderDeliveredEvent struct {
 	UserID        string    `json:"user_id"`
 	ReceiverName  string    `json:"receiver_name"`
 	DeliveryProof string    `json:"delivery_proof"`
+	Email         string    `json:"email,omitempty"`
 	DeliveredAt   time.Time `json:"delivered_at"`
 }
 
@@ -60,6 +66,8 @@ type OrderCancelledEvent struct {
 	CancelReason    string    `json:"cancel_reason"`
 	RefundAmount    float64   `json:"refund_amount"`
 	CancellationFee float64   `json:"cancellation_fee"`
+	TotalAmount     float64   `json:"total_amount,omitempty"`
+	SellerIDs       []string  `json:"seller_ids,omitempty"`
 	CancelledAt     time.Time `json:"cancelled_at"`
 }
 
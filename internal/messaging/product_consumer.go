@@ -0,0 +1,194 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/repositories"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/routing"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/services"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/templates"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ProductEventHandler handles restock and price change alerts and the
+// watch/unwatch events that maintain who receives them
+type ProductEventHandler struct {
+	fanout   *services.FanoutService
+	watchers *repositories.ProductWatcherRepository
+	log      *logrus.Logger
+}
+
+func NewProductEventHandler(
+	fanout *services.FanoutService,
+	watchers *repositories.ProductWatcherRepository,
+	log *logrus.Logger,
+) *ProductEventHandler {
+	return &ProductEventHandler{
+		fanout:   fanout,
+		watchers: watchers,
+		log:      log,
+	}
+}
+
+// Spec returns the product consumer
+func (h *ProductEventHandler) Spec(engine *routing.Engine) ConsumerSpec {
+	return ConsumerSpec{
+		Name: "product",
+		Handlers: map[string]EventHandler{
+			"product.restocked":     h.handleProductRestocked,
+			"product.price_changed": h.handleProductPriceChanged,
+			"product.watched":       h.handleProductWatched,
+			"product.unwatched":     h.handleProductUnwatched,
+		},
+		Rules: engine,
+	}
+}
+
+func (h *ProductEventHandler) handleProductRestocked(ctx context.Context, body []byte) error {
+	var event ProductRestockedEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal ProductRestockedEvent: %w", err)
+	}
+
+	productID, err := uuid.Parse(event.ProductID)
+	if err != nil {
+		return fmt.Errorf("invalid product_id %q: %w", event.ProductID, err)
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"product_id": event.ProductID,
+		"stock":      event.Stock,
+	}).Info("Processing product restocked event")
+
+	return h.fanout.Enqueue(services.FanoutJob{
+		Name: fmt.Sprintf("product.restocked:%s", event.ProductID),
+		Recipients: func(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+			return h.watchers.ListWatchers(ctx, productID, repositories.WatchRestock, nil, after, limit)
+		},
+		Build: func(userID uuid.UUID) *services.CreateNotificationRequest {
+			return &services.CreateNotificationRequest{
+				UserID:   userID.String(),
+				Type:     "product",
+				Category: "back_in_stock",
+				Template: TemplateProductRestocked,
+				Channels: []string{"email", "push", "in_app"},
+				Metadata: map[string]interface{}{
+					"product_id":   event.ProductID,
+					"product_name": event.ProductName,
+					"stock":        event.Stock,
+					"price":        event.Price,
+				},
+				Action: productAction(event.ProductID),
+			}
+		},
+		// A restock alert is one-off: the watch is removed once the user has
+		// been told, so the next restock does not alert them again
+		Sent: func(ctx context.Context, userID uuid.UUID) error {
+			return h.watchers.Unwatch(ctx, productID, userID, repositories.WatchRestock)
+		},
+	})
+}
+
+func (h *ProductEventHandler) handleProductPriceChanged(ctx context.Context, body []byte) error {
+	var event ProductPriceChangedEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal ProductPriceChangedEvent: %w", err)
+	}
+
+	// Only price drops are worth an alert
+	if event.NewPrice >= event.OldPrice {
+		return nil
+	}
+
+	productID, err := uuid.Parse(event.ProductID)
+	if err != nil {
+		return fmt.Errorf("invalid product_id %q: %w", event.ProductID, err)
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"product_id": event.ProductID,
+		"old_price":  event.OldPrice,
+		"new_price":  event.NewPrice,
+	}).Info("Processing product price drop event")
+
+	newPrice := event.NewPrice
+	return h.fanout.Enqueue(services.FanoutJob{
+		Name: fmt.Sprintf("product.price_dropped:%s", event.ProductID),
+		Recipients: func(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+			return h.watchers.ListWatchers(ctx, productID, repositories.WatchPriceDrop, &newPrice, after, limit)
+		},
+		Build: func(userID uuid.UUID) *services.CreateNotificationRequest {
+			return &services.CreateNotificationRequest{
+				UserID:   userID.String(),
+				Type:     "product",
+				Category: "price_drop",
+				Template: TemplateProductPriceDropped,
+				Channels: []string{"email", "push", "in_app"},
+				Metadata: map[string]interface{}{
+					"product_id":   event.ProductID,
+					"product_name": event.ProductName,
+					"old_price":    event.OldPrice,
+					"new_price":    event.NewPrice,
+				},
+				Action: productAction(event.ProductID),
+				Summary: []templates.EmailSummaryRow{
+					{Label: "Harga Sebelumnya", Value: templates.FormatRupiah(event.OldPrice)},
+					{Label: "Harga Sekarang", Value: templates.FormatRupiah(event.NewPrice)},
+				},
+			}
+		},
+	})
+}
+
+func (h *ProductEventHandler) handleProductWatched(ctx context.Context, body []byte) error {
+	var event ProductWatchedEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal ProductWatchedEvent: %w", err)
+	}
+
+	productID, userID, err := parseWatchIDs(event.ProductID, event.UserID, event.Kind)
+	if err != nil {
+		return err
+	}
+
+	return h.watchers.Watch(ctx, productID, userID, event.Kind, event.TargetPrice)
+}
+
+func (h *ProductEventHandler) handleProductUnwatched(ctx context.Context, body []byte) error {
+	var event ProductUnwatchedEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal ProductUnwatchedEvent: %w", err)
+	}
+
+	productID, userID, err := parseWatchIDs(event.ProductID, event.UserID, event.Kind)
+	if err != nil {
+		return err
+	}
+
+	return h.watchers.Unwatch(ctx, productID, userID, event.Kind)
+}
+
+func parseWatchIDs(productID, userID, kind string) (uuid.UUID, uuid.UUID, error) {
+	if kind != repositories.WatchRestock && kind != repositories.WatchPriceDrop {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid watch kind %q", kind)
+	}
+	product, err := uuid.Parse(productID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid product_id %q: %w", productID, err)
+	}
+	user, err := uuid.Parse(userID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid user_id %q: %w", userID, err)
+	}
+	return product, user, nil
+}
+
+func productAction(productID string) *templates.EmailAction {
+	return &templates.EmailAction{
+		Label: "Lihat Produk",
+		URL:   fmt.Sprintf("/products/%s", productID),
+	}
+}
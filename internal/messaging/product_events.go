@@ -0,0 +1,36 @@
+package messaging
+
+import "time"
+
+// ProductRestockedEvent for alerting watchers when a sold-out product is back
+type ProductRestockedEvent struct {
+	ProductID   string    `json:"product_id"`
+	ProductName string    `json:"product_name"`
+	Stock       int       `json:"stock"`
+	Price       float64   `json:"price"`
+	RestockedAt time.Time `json:"restocked_at"`
+}
+
+// ProductPriceChangedEvent for alerting watchers when a product gets cheaper
+type ProductPriceChangedEvent struct {
+	ProductID   string    `json:"product_id"`
+	ProductName string    `json:"product_name"`
+	OldPrice    float64   `json:"old_price"`
+	NewPrice    float64   `json:"new_price"`
+	ChangedAt   time.Time `json:"changed_at"`
+}
+
+// ProductWatchedEvent for subscribing a user to product alerts
+type ProductWatchedEvent struct {
+	ProductID   string   `json:"product_id"`
+	UserID      string   `json:"user_id"`
+	Kind        string   `json:"kind"` // restock, price_drop
+	TargetPrice *float64 `json:"target_price"`
+}
+
+// ProductUnwatchedEvent for unsubscribing a user from product alerts
+type ProductUnwatchedEvent struct {
+	ProductID string `json:"product_id"`
+	UserID    string `json:"user_id"`
+	Kind      string `json:"kind"`
+}
@@ -0,0 +1,49 @@
+package messaging
+
+import (
+	_ "embed"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/templates"
+)
+
+// DefaultRules is the built-in routing configuration, used when no rules
+// file is configured
+//
+//go:embed default_rules.json
+var DefaultRules []byte
+
+// EventSchemas returns the schemas of every event type with a Go struct, so
+// routing rules and their templates can be validated at startup
+func EventSchemas() map[string]templates.Schema {
+	events := map[string]interface{}{
+		"order.created":          OrderCreatedEvent{},
+		"order.paid":             OrderPaidEvent{},
+		"order.shipped":          OrderShippedEvent{},
+		"order.delivered":        OrderDeliveredEvent{},
+		"order.cancelled":        OrderCancelledEvent{},
+		"order.refunded":         OrderRefundedEvent{},
+		"order.status.changed":   OrderStatusChangedEvent{},
+		"blog.published":         BlogPublishedEvent{},
+		"blog.author.followed":   AuthorFollowedEvent{},
+		"blog.author.unfollowed": AuthorUnfollowedEvent{},
+		"comment.added":          CommentAddedEvent{},
+		"comment.replied":        CommentAddedEvent{},
+		"product.restocked":      ProductRestockedEvent{},
+		"product.price_changed":  ProductPriceChangedEvent{},
+		"product.watched":        ProductWatchedEvent{},
+		"product.unwatched":      ProductUnwatchedEvent{},
+
+		"shipment.tracking.updated": ShipmentTrackingUpdatedEvent{},
+
+		"account.password.changed": PasswordChangedEvent{},
+		"account.login.new_device": NewDeviceLoginEvent{},
+		"account.email.changed":    EmailChangedEvent{},
+		"account.otp.requested":    OTPRequestedEvent{},
+	}
+
+	schemas := make(map[string]templates.Schema, len(events))
+	for eventType, event := range events {
+		schemas[eventType] = templates.SchemaOf(event)
+	}
+	return schemas
+}
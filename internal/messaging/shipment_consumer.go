@@ -0,0 +1,14 @@
+package messaging
+
+import "github.com/RehanAthallahAzhar/tokohobby-notifications/internal/routing"
+
+// ShipmentConsumerSpec returns the courier tracking consumer. Tracking
+// updates are handled entirely by routing rules, which share the
+// "shipment:{order_id}" collapse key with order.shipped and order.delivered so
+// each order has a single live shipment notification.
+func ShipmentConsumerSpec(engine *routing.Engine) ConsumerSpec {
+	return ConsumerSpec{
+		Name:  "shipment",
+		Rules: engine,
+	}
+}
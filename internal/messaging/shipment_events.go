@@ -0,0 +1,24 @@
+package messaging
+
+import "time"
+
+// Courier tracking statuses carried by ShipmentTrackingUpdatedEvent
+const (
+	ShipmentInTransit      = "in_transit"
+	ShipmentOutForDelivery = "out_for_delivery"
+	ShipmentDeliveryFailed = "delivery_failed"
+)
+
+// ShipmentTrackingUpdatedEvent is a courier tracking update for a shipped order
+type ShipmentTrackingUpdatedEvent struct {
+	OrderID          string    `json:"order_id"`
+	UserID           string    `json:"user_id"`
+	TrackingNumber   string    `json:"tracking_number"`
+	Courier          string    `json:"courier"`
+	Status           string    `json:"status"`
+	Location         string    `json:"location"`
+	Description      string    `json:"description"`
+	EstimatedArrival time.Time `json:"estimated_arrival"`
+	PhoneNumber      string    `json:"phone_number,omitempty"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
@@ -0,0 +1,166 @@
+package messaging
+
+import (
+	"fmt"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/templates"
+)
+
+// Template names used by the event consumers. Order templates are defined
+// in the routing rules (default_rules.json).
+const (
+	TemplatePaymentReminder = "order.payment_reminder"
+	TemplateReviewRequest   = "order.review_request"
+
+	TemplateBlogPublished    = "blog.published"
+	TemplateCommentAdded     = "comment.added"
+	TemplateCommentReplied   = "comment.replied"
+	TemplateCommentMentioned = "comment.mentioned"
+
+	TemplateProductRestocked    = "product.restocked"
+	TemplateProductPriceDropped = "product.price_dropped"
+)
+
+// RegisterTemplates registers the templates used by Go handlers, validating
+// each one against the fields declared by its event struct
+func RegisterTemplates(reg *templates.Registry) error {
+	definitions := []struct {
+		template templates.Template
+		event    interface{}
+	}{
+		{
+			template: templates.Template{
+				Name:    TemplatePaymentReminder,
+				Title:   "Selesaikan Pembayaranmu",
+				Message: "Pesanan #{{order_id}} sebesar {{total_amount|rupiah}} masih menunggu pembayaran via {{payment_method}}. Selesaikan sekarang sebelum pesanan dibatalkan otomatis.",
+				Channels: map[string]templates.ChannelContent{
+					"email": {
+						Subject: "Pesanan #{{order_id}} Menunggu Pembayaran",
+						Preview: "Jangan sampai kehabisan, selesaikan pembayaranmu sekarang.",
+					},
+					"push": {Body: "Pesanan #{{order_id}} ({{total_amount|rupiah}}) menunggu pembayaranmu."},
+				},
+				ProviderTemplates: map[string]templates.ProviderTemplate{
+					"whatsapp": {
+						Name:       "payment_reminder",
+						Language:   "id",
+						Parameters: []string{"{{order_id}}", "{{total_amount|rupiah}}"},
+					},
+				},
+				Defaults: map[string]string{"payment_method": "metode pilihanmu"},
+			},
+			event: OrderCreatedEvent{},
+		},
+		{
+			template: templates.Template{
+				Name:    TemplateReviewRequest,
+				Title:   "Bagaimana Pesananmu?",
+				Message: "Pesanan #{{order_id}} sudah sampai beberapa hari lalu. Ceritakan pengalamanmu dan bantu kolektor lain memilih dengan memberi ulasan.",
+				Channels: map[string]templates.ChannelContent{
+					"email": {
+						Subject: "Beri Ulasan untuk Pesanan #{{order_id}}",
+						Preview: "Ulasanmu sangat berarti untuk penjual dan pembeli lain.",
+					},
+					"push": {Body: "Sudah puas dengan pesanan #{{order_id}}? Yuk beri ulasan!"},
+				},
+			},
+			event: OrderDeliveredEvent{},
+		},
+		{
+			template: templates.Template{
+				Name:    TemplateBlogPublished,
+				Title:   "New Blog from {{author_name}}",
+				Message: "{{author_name}} published a new blog: {{title}}",
+				Channels: map[string]templates.ChannelContent{
+					"email": {Preview: "{{excerpt}}"},
+					"push":  {Body: "{{title}}"},
+				},
+			},
+			event: BlogPublishedEvent{},
+		},
+		{
+			template: templates.Template{
+				Name:    TemplateCommentAdded,
+				Title:   "New Comment",
+				Message: "{{commenter_name}} commented on your blog '{{blog_title}}': {{comment}}",
+				Channels: map[string]templates.ChannelContent{
+					"email": {
+						Subject: "{{commenter_name}} commented on \"{{blog_title}}\"",
+						Preview: "{{comment}}",
+					},
+					"push": {
+						Subject: "{{commenter_name}} commented on your blog",
+						Body:    "{{comment}}",
+					},
+				},
+			},
+			event: CommentAddedEvent{},
+		},
+		{
+			template: templates.Template{
+				Name:    TemplateCommentReplied,
+				Title:   "New Reply",
+				Message: "{{commenter_name}} replied to your comment on '{{blog_title}}': {{comment}}",
+				Channels: map[string]templates.ChannelContent{
+					"email": {
+						Subject: "{{commenter_name}} replied to your comment",
+						Preview: "{{comment}}",
+					},
+					"push": {
+						Subject: "{{commenter_name}} replied to your comment",
+						Body:    "{{comment}}",
+					},
+				},
+			},
+			event: CommentAddedEvent{},
+		},
+		{
+			template: templates.Template{
+				Name:    TemplateCommentMentioned,
+				Title:   "You Were Mentioned",
+				Message: "{{commenter_name}} mentioned you in a comment on '{{blog_title}}': {{comment}}",
+				Channels: map[string]templates.ChannelContent{
+					"email": {
+						Subject: "{{commenter_name}} mentioned you",
+						Preview: "{{comment}}",
+					},
+					"push": {
+						Subject: "{{commenter_name}} mentioned you",
+						Body:    "{{comment}}",
+					},
+				},
+			},
+			event: CommentAddedEvent{},
+		},
+		{
+			template: templates.Template{
+				Name:    TemplateProductRestocked,
+				Title:   "Stok Tersedia Kembali",
+				Message: "{{product_name}} sudah tersedia lagi dengan harga {{price|rupiah}}. Yuk checkout sebelum kehabisan!",
+				Channels: map[string]templates.ChannelContent{
+					"push": {Body: "{{product_name}} sudah tersedia lagi!"},
+				},
+			},
+			event: ProductRestockedEvent{},
+		},
+		{
+			template: templates.Template{
+				Name:    TemplateProductPriceDropped,
+				Title:   "Harga Turun!",
+				Message: "Harga {{product_name}} turun dari {{old_price|rupiah}} menjadi {{new_price|rupiah}}",
+				Channels: map[string]templates.ChannelContent{
+					"email": {Subject: "Harga {{product_name}} Turun!"},
+				},
+			},
+			event: ProductPriceChangedEvent{},
+		},
+	}
+
+	for _, def := range definitions {
+		if err := reg.Register(def.template, def.event); err != nil {
+			return fmt.Errorf("invalid template %s: %w", def.template.Name, err)
+		}
+	}
+
+	return nil
+}
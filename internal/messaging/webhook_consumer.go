@@ -0,0 +1,103 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/repositories"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// WebhookSubscribedEvent registers (or updates) a webhook endpoint for a user
+type WebhookSubscribedEvent struct {
+	UserID string   `json:"user_id"`
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Types  []string `json:"types,omitempty"`
+}
+
+// WebhookUnsubscribedEvent removes a webhook endpoint
+type WebhookUnsubscribedEvent struct {
+	UserID string `json:"user_id"`
+	URL    string `json:"url"`
+}
+
+// WebhookEventHandler maintains webhook subscriptions
+type WebhookEventHandler struct {
+	webhooks *repositories.WebhookRepository
+	log      *logrus.Logger
+}
+
+func NewWebhookEventHandler(webhooks *repositories.WebhookRepository, log *logrus.Logger) *WebhookEventHandler {
+	return &WebhookEventHandler{
+		webhooks: webhooks,
+		log:      log,
+	}
+}
+
+// Spec returns the webhook subscription consumer
+func (h *WebhookEventHandler) Spec() ConsumerSpec {
+	return ConsumerSpec{
+		Name: "webhook",
+		Handlers: map[string]EventHandler{
+			"webhook.subscribed":   h.handleSubscribed,
+			"webhook.unsubscribed": h.handleUnsubscribed,
+		},
+	}
+}
+
+func (h *WebhookEventHandler) handleSubscribed(ctx context.Context, body []byte) error {
+	var event WebhookSubscribedEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal WebhookSubscribedEvent: %w", err)
+	}
+
+	userID, err := uuid.Parse(event.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid user_id %q: %w", event.UserID, err)
+	}
+	if err := validateWebhookURL(event.URL); err != nil {
+		return err
+	}
+	if event.Secret == "" {
+		return fmt.Errorf("webhook secret is required")
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"user_id": event.UserID,
+		"url":     event.URL,
+		"types":   event.Types,
+	}).Info("Registering webhook")
+
+	return h.webhooks.Subscribe(ctx, userID, event.URL, event.Secret, event.Types)
+}
+
+func (h *WebhookEventHandler) handleUnsubscribed(ctx context.Context, body []byte) error {
+	var event WebhookUnsubscribedEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal WebhookUnsubscribedEvent: %w", err)
+	}
+
+	userID, err := uuid.Parse(event.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid user_id %q: %w", event.UserID, err)
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"user_id": event.UserID,
+		"url":     event.URL,
+	}).Info("Removing webhook")
+
+	return h.webhooks.Unsubscribe(ctx, userID, event.URL)
+}
+
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return fmt.Errorf("invalid webhook url %q", raw)
+	}
+	return nil
+}
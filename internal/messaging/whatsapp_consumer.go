@@ -0,0 +1,65 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/repositories"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/senders"
+	"github.com/sirupsen/logrus"
+)
+
+// WhatsAppMessageReceivedEvent is published by the WhatsApp webhook receiver
+// for every inbound customer message
+type WhatsAppMessageReceivedEvent struct {
+	PhoneNumber string    `json:"phone_number"`
+	ReceivedAt  time.Time `json:"received_at"`
+}
+
+// WhatsAppEventHandler tracks inbound WhatsApp messages, which open the
+// 24-hour window for free-form messages
+type WhatsAppEventHandler struct {
+	sessions *repositories.WhatsAppSessionRepository
+	log      *logrus.Logger
+}
+
+func NewWhatsAppEventHandler(sessions *repositories.WhatsAppSessionRepository, log *logrus.Logger) *WhatsAppEventHandler {
+	return &WhatsAppEventHandler{
+		sessions: sessions,
+		log:      log,
+	}
+}
+
+// Spec returns the WhatsApp consumer
+func (h *WhatsAppEventHandler) Spec() ConsumerSpec {
+	return ConsumerSpec{
+		Name: "whatsapp",
+		Handlers: map[string]EventHandler{
+			"whatsapp.message.received": h.handleMessageReceived,
+		},
+	}
+}
+
+func (h *WhatsAppEventHandler) handleMessageReceived(ctx context.Context, body []byte) error {
+	var event WhatsAppMessageReceivedEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal WhatsAppMessageReceivedEvent: %w", err)
+	}
+
+	// Cloud API webhooks carry the wa_id without "+"
+	phone, err := senders.NormalizePhoneE164("+" + strings.TrimPrefix(event.PhoneNumber, "+"))
+	if err != nil {
+		return err
+	}
+
+	receivedAt := event.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = time.Now()
+	}
+
+	h.log.WithField("phone_number", phone).Debug("WhatsApp session window opened")
+	return h.sessions.Touch(ctx, phone, receivedAt)
+}
@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/entities"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// DeviceTokenRepository stores the push targets of users' devices
+type DeviceTokenRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewDeviceTokenRepository(db *pgxpool.Pool, log *logrus.Logger) *DeviceTokenRepository {
+	return &DeviceTokenRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Register stores a device token, moving it to userID if another user
+// registered it before. Registering again re-activates the token.
+func (r *DeviceTokenRepository) Register(ctx context.Context, token *entities.DeviceToken) error {
+	query := `
+		INSERT INTO device_tokens (user_id, platform, token, p256dh, auth, created_at, updated_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NOW(), NOW())
+		ON CONFLICT (platform, token) DO UPDATE
+		SET user_id = EXCLUDED.user_id,
+		    p256dh = EXCLUDED.p256dh,
+		    auth = EXCLUDED.auth,
+		    active = TRUE,
+		    updated_at = NOW()
+	`
+
+	_, err := r.db.Exec(ctx, query, token.UserID, token.Platform, token.Token, token.P256dh, token.Auth)
+	if err != nil {
+		return fmt.Errorf("failed to insert device token: %w", err)
+	}
+	return nil
+}
+
+// Unregister removes a device token
+func (r *DeviceTokenRepository) Unregister(ctx context.Context, platform, token string) error {
+	query := `DELETE FROM device_tokens WHERE platform = $1 AND token = $2`
+
+	if _, err := r.db.Exec(ctx, query, platform, token); err != nil {
+		return fmt.Errorf("failed to delete device token: %w", err)
+	}
+	return nil
+}
+
+// ListActive returns the user's active tokens on platform
+func (r *DeviceTokenRepository) ListActive(ctx context.Context, userID uuid.UUID, platform string) ([]entities.DeviceToken, error) {
+	query := `
+		SELECT id, user_id, platform, token, COALESCE(p256dh, ''), COALESCE(auth, ''),
+		       active, last_used_at, created_at, updated_at
+		FROM device_tokens
+		WHERE user_id = $1 AND platform = $2 AND active = TRUE
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, platform)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query device tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []entities.DeviceToken
+	for rows.Next() {
+		var token entities.DeviceToken
+		err := rows.Scan(
+			&token.ID,
+			&token.UserID,
+			&token.Platform,
+			&token.Token,
+			&token.P256dh,
+			&token.Auth,
+			&token.Active,
+			&token.LastUsedAt,
+			&token.CreatedAt,
+			&token.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan device token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// MarkUsed records a successful delivery to a token
+func (r *DeviceTokenRepository) MarkUsed(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE device_tokens SET last_used_at = NOW() WHERE id = $1`
+
+	_, err := r.db.Exec(ctx, query, id)
+	return err
+}
+
+// Deactivate stops deliveries to a token the push service no longer accepts
+func (r *DeviceTokenRepository) Deactivate(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE device_tokens SET active = FALSE, updated_at = NOW() WHERE id = $1`
+
+	if _, err := r.db.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to deactivate device token: %w", err)
+	}
+	return nil
+}
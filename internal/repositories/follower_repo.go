@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// FollowerRepository stores which users follow which blog authors
+type FollowerRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewFollowerRepository(db *pgxpool.Pool, log *logrus.Logger) *FollowerRepository {
+	return &FollowerRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Follow records that followerID follows authorID
+func (r *FollowerRepository) Follow(ctx context.Context, authorID, followerID uuid.UUID) error {
+	query := `
+		INSERT INTO author_followers (author_id, follower_id, created_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (author_id, follower_id) DO NOTHING
+	`
+
+	if _, err := r.db.Exec(ctx, query, authorID, followerID); err != nil {
+		return fmt.Errorf("failed to insert follower: %w", err)
+	}
+
+	return nil
+}
+
+// Unfollow removes the follow relation, if any
+func (r *FollowerRepository) Unfollow(ctx context.Context, authorID, followerID uuid.UUID) error {
+	query := `
+		DELETE FROM author_followers
+		WHERE author_id = $1 AND follower_id = $2
+	`
+
+	if _, err := r.db.Exec(ctx, query, authorID, followerID); err != nil {
+		return fmt.Errorf("failed to delete follower: %w", err)
+	}
+
+	return nil
+}
+
+// ListFollowers returns up to limit follower IDs of an author, ordered by ID
+// and starting after the given ID (uuid.Nil for the first page)
+func (r *FollowerRepository) ListFollowers(ctx context.Context, authorID, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	query := `
+		SELECT follower_id FROM author_followers
+		WHERE author_id = $1 AND follower_id > $2
+		ORDER BY follower_id
+		LIMIT $3
+	`
+
+	rows, err := r.db.Query(ctx, query, authorID, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query followers: %w", err)
+	}
+	defer rows.Close()
+
+	var followers []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan follower: %w", err)
+		}
+		followers = append(followers, id)
+	}
+
+	return followers, rows.Err()
+}
@@ -0,0 +1,147 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/entities"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// FollowupRepository persists order follow-ups so they survive restarts
+type FollowupRepository struct {
+	jobQueue
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewFollowupRepository(db *pgxpool.Pool, log *logrus.Logger) *FollowupRepository {
+	return &FollowupRepository{
+		jobQueue: jobQueue{db: db, table: "order_followups", dueColumn: "due_at"},
+		db:       db,
+		log:      log,
+	}
+}
+
+// Schedule inserts a pending follow-up. It is a no-op when the follow-up
+// with the same kind and step already exists for the order, in any status,
+// or follow-ups of this kind were cancelled for the order, which happens
+// when events arrive out of order.
+func (r *FollowupRepository) Schedule(ctx context.Context, f *entities.OrderFollowup) error {
+	payloadJSON, err := json.Marshal(f.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	query := `
+		INSERT INTO order_followups (order_id, user_id, kind, step, due_at, payload, status, created_at, updated_at)
+		SELECT $1, $2, $3, $4, $5, $6, 'pending', NOW(), NOW()
+		WHERE NOT EXISTS (
+			SELECT 1 FROM order_followups
+			WHERE order_id = $1 AND kind = $3 AND status = 'cancelled'
+		)
+		ON CONFLICT (order_id, kind, step) DO NOTHING
+	`
+
+	if _, err := r.db.Exec(ctx, query, f.OrderID, f.UserID, f.Kind, f.Step, f.DueAt, payloadJSON); err != nil {
+		return fmt.Errorf("failed to insert followup: %w", err)
+	}
+
+	return nil
+}
+
+// Cancel cancels the pending follow-ups of the given kinds for an order. If
+// there are none yet, a cancelled marker is stored so a late Schedule for the
+// same order is ignored.
+func (r *FollowupRepository) Cancel(ctx context.Context, orderID string, userID uuid.UUID, kinds []string) (int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var cancelled int64
+	for _, kind := range kinds {
+		result, err := tx.Exec(ctx, `
+			UPDATE order_followups
+			SET status = 'cancelled', updated_at = NOW()
+			WHERE order_id = $1 AND kind = $2 AND status = 'pending'
+		`, orderID, kind)
+		if err != nil {
+			return 0, fmt.Errorf("failed to cancel followups: %w", err)
+		}
+		cancelled += result.RowsAffected()
+
+		if result.RowsAffected() > 0 {
+			continue
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO order_followups (order_id, user_id, kind, due_at, status, created_at, updated_at)
+			SELECT $1, $2, $3, NOW(), 'cancelled', NOW(), NOW()
+			WHERE NOT EXISTS (
+				SELECT 1 FROM order_followups WHERE order_id = $1 AND kind = $3
+			)
+		`, orderID, userID, kind)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert cancelled marker: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit cancellation: %w", err)
+	}
+
+	return cancelled, nil
+}
+
+// ClaimDue marks up to limit due follow-ups as processing and returns them.
+// Follow-ups stuck in processing for longer than staleAfter (e.g. after a
+// crash) are claimed again.
+func (r *FollowupRepository) ClaimDue(ctx context.Context, limit int, staleAfter time.Duration) ([]entities.OrderFollowup, error) {
+	rows, err := r.claimDue(ctx, limit, staleAfter, `
+		id, order_id, user_id, kind, step, due_at, payload, status,
+		attempts, COALESCE(last_error, ''), created_at, updated_at
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var followups []entities.OrderFollowup
+	for rows.Next() {
+		var f entities.OrderFollowup
+		var payloadJSON []byte
+
+		err := rows.Scan(
+			&f.ID,
+			&f.OrderID,
+			&f.UserID,
+			&f.Kind,
+			&f.Step,
+			&f.DueAt,
+			&payloadJSON,
+			&f.Status,
+			&f.Attempts,
+			&f.LastError,
+			&f.CreatedAt,
+			&f.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan followup: %w", err)
+		}
+
+		if err := json.Unmarshal(payloadJSON, &f.Payload); err != nil {
+			r.log.WithError(err).Warn("Failed to unmarshal followup payload")
+			f.Payload = make(map[string]interface{})
+		}
+
+		followups = append(followups, f)
+	}
+
+	return followups, rows.Err()
+}
@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// jobQueue is the claim and status handling shared by tables that are polled
+// for due work (order_followups, scheduled_notifications). A row is pending
+// until claimed, processing while a worker runs it, then sent, failed, or
+// pending again for a retry. Rows need id, status, attempts, last_error and
+// updated_at columns, plus the column holding when they are due.
+type jobQueue struct {
+	db        *pgxpool.Pool
+	table     string
+	dueColumn string
+}
+
+// claimDue marks up to limit due rows as processing and returns the given
+// columns of them. Rows stuck in processing for longer than staleAfter (e.g.
+// after a crash) are claimed again.
+func (q *jobQueue) claimDue(ctx context.Context, limit int, staleAfter time.Duration, columns string) (pgx.Rows, error) {
+	query := fmt.Sprintf(`
+		UPDATE %[1]s
+		SET status = 'processing', attempts = attempts + 1, updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM %[1]s
+			WHERE (status = 'pending' AND %[2]s <= NOW())
+			   OR (status = 'processing' AND updated_at < NOW() - $2 * INTERVAL '1 second')
+			ORDER BY %[2]s
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING %[3]s
+	`, q.table, q.dueColumn, columns)
+
+	rows, err := q.db.Query(ctx, query, limit, int(staleAfter.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim %s: %w", q.table, err)
+	}
+	return rows, nil
+}
+
+// MarkSent marks a claimed row as done
+func (q *jobQueue) MarkSent(ctx context.Context, id uuid.UUID) error {
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET status = 'sent', last_error = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'processing'
+	`, q.table)
+
+	_, err := q.db.Exec(ctx, query, id)
+	return err
+}
+
+// Retry puts a claimed row back to pending at retryAt, keeping the error
+func (q *jobQueue) Retry(ctx context.Context, id uuid.UUID, retryAt time.Time, lastErr string) error {
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET status = 'pending', %s = $2, last_error = $3, updated_at = NOW()
+		WHERE id = $1 AND status = 'processing'
+	`, q.table, q.dueColumn)
+
+	_, err := q.db.Exec(ctx, query, id, retryAt, lastErr)
+	return err
+}
+
+// MarkFailed gives up on a claimed row
+func (q *jobQueue) MarkFailed(ctx context.Context, id uuid.UUID, lastErr string) error {
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET status = 'failed', last_error = $2, updated_at = NOW()
+		WHERE id = $1 AND status = 'processing'
+	`, q.table)
+
+	_, err := q.db.Exec(ctx, query, id, lastErr)
+	return err
+}
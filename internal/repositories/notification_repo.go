@@ -3,11 +3,13 @@ package repositories
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/entities"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sirupsen/logrus"
 )
@@ -25,21 +27,60 @@ func NewNotificationRepository(db *pgxpool.Pool, log *logrus.Logger) *Notificati
 	}
 }
 
-// Create inserts a new notification
+// ErrStaleNotification is returned by Create when the user already has a
+// notification with the same collapse key for a later event
+var ErrStaleNotification = errors.New("notification is older than the one it would replace")
+
+// Create inserts a new notification. A notification with a collapse key
+// replaces the user's existing notification with the same key, which is
+// marked unread again and moved to the top of the inbox; notif.ID is set to
+// the ID of the stored row. The existing notification is kept, and
+// ErrStaleNotification returned, when its event time is later than
+// notif.EventTime: events can arrive out of order.
 func (r *NotificationRepository) Create(ctx context.Context, notif *entities.Notification) error {
 	metadataJSON, err := json.Marshal(notif.Metadata)
 	if err != nil {
 		return fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 
+	priority := notif.Priority
+	if priority == "" {
+		priority = "normal"
+	}
+
+	var collapseKey *string
+	if notif.CollapseKey != "" {
+		collapseKey = &notif.CollapseKey
+	}
+
 	query := `
 		INSERT INTO notifications (
 			id, user_id, type, category, title, message, metadata, 
-			channels, status, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			channels, priority, status, collapse_key, event_time, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (user_id, collapse_key) WHERE collapse_key IS NOT NULL DO UPDATE SET
+			type = EXCLUDED.type,
+			category = EXCLUDED.category,
+			title = EXCLUDED.title,
+			message = EXCLUDED.message,
+			metadata = EXCLUDED.metadata,
+			channels = EXCLUDED.channels,
+			priority = EXCLUDED.priority,
+			status = EXCLUDED.status,
+			is_read = FALSE,
+			read_at = NULL,
+			email_sent_at = NULL,
+			push_sent_at = NULL,
+			event_time = EXCLUDED.event_time,
+			created_at = EXCLUDED.created_at,
+			updated_at = EXCLUDED.updated_at
+		WHERE notifications.event_time IS NULL
+		   OR EXCLUDED.event_time IS NULL
+		   OR EXCLUDED.event_time >= notifications.event_time
+		RETURNING id
 	`
 
-	_, err = r.db.Exec(ctx, query,
+	err = r.db.QueryRow(ctx, query,
 		notif.ID,
 		notif.UserID,
 		notif.Type,
@@ -48,11 +89,18 @@ func (r *NotificationRepository) Create(ctx context.Context, notif *entities.Not
 		notif.Message,
 		metadataJSON,
 		notif.Channels,
+		priority,
 		notif.Status,
+		collapseKey,
+		notif.EventTime,
 		time.Now(),
 		time.Now(),
-	)
+	).Scan(&notif.ID)
 
+	if errors.Is(err, pgx.ErrNoRows) {
+		// The conflicting row was kept, so nothing was returned
+		return ErrStaleNotification
+	}
 	if err != nil {
 		return fmt.Errorf("failed to insert notification: %w", err)
 	}
@@ -65,7 +113,7 @@ func (r *NotificationRepository) Create(ctx context.Context, notif *entities.Not
 func (r *NotificationRepository) GetUserNotifications(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit int) ([]entities.Notification, error) {
 	query := `
 		SELECT id, user_id, type, category, title, message, metadata, channels,
-		       status, is_read, read_at, email_sent_at, push_sent_at,
+		       priority, status, COALESCE(collapse_key, ''), is_read, read_at, email_sent_at, push_sent_at,
 		       retry_count, last_error, created_at, updated_at, expires_at
 		FROM notifications
 		WHERE user_id = $1
@@ -94,7 +142,9 @@ func (r *NotificationRepository) GetUserNotifications(ctx context.Context, userI
 			&notif.Message,
 			&metadataJSON,
 			&notif.Channels,
+			&notif.Priority,
 			&notif.Status,
+			&notif.CollapseKey,
 			&notif.IsRead,
 			&notif.ReadAt,
 			&notif.EmailSentAt,
@@ -157,6 +207,23 @@ func (r *NotificationRepository) UpdateStatus(ctx context.Context, notifID uuid.
 	return nil
 }
 
+// UpdateDeferredStatus updates the status of a notification that is waiting
+// for deferred deliveries, leaving it alone if another channel failed
+func (r *NotificationRepository) UpdateDeferredStatus(ctx context.Context, notifID uuid.UUID, status string) error {
+	query := `
+		UPDATE notifications
+		SET status = $2, updated_at = NOW()
+		WHERE id = $1 AND status = 'deferred'
+	`
+
+	_, err := r.db.Exec(ctx, query, notifID, status)
+	if err != nil {
+		return fmt.Errorf("failed to update deferred status: %w", err)
+	}
+
+	return nil
+}
+
 // UpdateEmailSentAt updates email sent timestamp
 func (r *NotificationRepository) UpdateEmailSentAt(ctx context.Context, notifID uuid.UUID) error {
 	query := `
@@ -181,6 +248,28 @@ func (r *NotificationRepository) UpdatePushSentAt(ctx context.Context, notifID u
 	return err
 }
 
+// RecordDelivery stores the outcome of sending a notification on a channel
+func (r *NotificationRepository) RecordDelivery(ctx context.Context, delivery *entities.NotificationDelivery) error {
+	query := `
+		INSERT INTO notification_deliveries (id, notification_id, channel, provider, status, error, created_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NOW())
+	`
+
+	_, err := r.db.Exec(ctx, query,
+		delivery.ID,
+		delivery.NotificationID,
+		delivery.Channel,
+		delivery.Provider,
+		delivery.Status,
+		delivery.Error,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert delivery: %w", err)
+	}
+
+	return nil
+}
+
 // GetUnreadCount counts unread notifications
 func (r *NotificationRepository) GetUnreadCount(ctx context.Context, userID uuid.UUID) (int, error) {
 	query := `
@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/entities"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// PreferenceRepository reads users' notification preferences
+type PreferenceRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewPreferenceRepository(db *pgxpool.Pool, log *logrus.Logger) *PreferenceRepository {
+	return &PreferenceRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Get returns the user's preferences, or nil if they never saved any
+func (r *PreferenceRepository) Get(ctx context.Context, userID uuid.UUID) (*entities.NotificationPreference, error) {
+	query := `
+		SELECT id, user_id, email_enabled, push_enabled, in_app_enabled,
+		       order_notifications, account_notifications, product_notifications,
+		       quiet_hours_enabled,
+		       COALESCE(to_char(quiet_hours_start, 'HH24:MI'), ''),
+		       COALESCE(to_char(quiet_hours_end, 'HH24:MI'), ''),
+		       created_at, updated_at
+		FROM notification_preferences
+		WHERE user_id = $1
+	`
+
+	var pref entities.NotificationPreference
+	var orderJSON, accountJSON, productJSON []byte
+	var quietStart, quietEnd string
+
+	err := r.db.QueryRow(ctx, query, userID).Scan(
+		&pref.ID,
+		&pref.UserID,
+		&pref.EmailEnabled,
+		&pref.PushEnabled,
+		&pref.InAppEnabled,
+		&orderJSON,
+		&accountJSON,
+		&productJSON,
+		&pref.QuietHoursEnabled,
+		&quietStart,
+		&quietEnd,
+		&pref.CreatedAt,
+		&pref.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query preferences: %w", err)
+	}
+
+	for _, field := range []struct {
+		data []byte
+		dest *map[string]bool
+	}{
+		{orderJSON, &pref.OrderNotifications},
+		{accountJSON, &pref.AccountNotifications},
+		{productJSON, &pref.ProductNotifications},
+	} {
+		if len(field.data) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(field.data, field.dest); err != nil {
+			r.log.WithError(err).Warn("Failed to unmarshal category preferences")
+		}
+	}
+
+	pref.QuietHoursStart = parseClock(quietStart)
+	pref.QuietHoursEnd = parseClock(quietEnd)
+
+	return &pref, nil
+}
+
+// parseClock parses an HH:MM time of day
+func parseClock(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
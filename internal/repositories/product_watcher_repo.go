@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// Product watch kinds
+const (
+	WatchRestock   = "restock"
+	WatchPriceDrop = "price_drop"
+)
+
+// ProductWatcherRepository stores users watching products for alerts
+type ProductWatcherRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewProductWatcherRepository(db *pgxpool.Pool, log *logrus.Logger) *ProductWatcherRepository {
+	return &ProductWatcherRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Watch subscribes a user to alerts of the given kind. targetPrice is only
+// used for price drop alerts and may be nil.
+func (r *ProductWatcherRepository) Watch(ctx context.Context, productID, userID uuid.UUID, kind string, targetPrice *float64) error {
+	query := `
+		INSERT INTO product_watchers (product_id, user_id, kind, target_price, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (product_id, kind, user_id) DO UPDATE SET target_price = EXCLUDED.target_price
+	`
+
+	if _, err := r.db.Exec(ctx, query, productID, userID, kind, targetPrice); err != nil {
+		return fmt.Errorf("failed to insert product watcher: %w", err)
+	}
+
+	return nil
+}
+
+// Unwatch removes a subscription, if any
+func (r *ProductWatcherRepository) Unwatch(ctx context.Context, productID, userID uuid.UUID, kind string) error {
+	query := `
+		DELETE FROM product_watchers
+		WHERE product_id = $1 AND user_id = $2 AND kind = $3
+	`
+
+	if _, err := r.db.Exec(ctx, query, productID, userID, kind); err != nil {
+		return fmt.Errorf("failed to delete product watcher: %w", err)
+	}
+
+	return nil
+}
+
+// ListWatchers returns up to limit users watching a product for kind, ordered
+// by ID and starting after the given ID. When price is set, watchers whose
+// target price is below it are skipped.
+func (r *ProductWatcherRepository) ListWatchers(ctx context.Context, productID uuid.UUID, kind string, price *float64, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	query := `
+		SELECT user_id FROM product_watchers
+		WHERE product_id = $1 AND kind = $2
+		AND ($3::numeric IS NULL OR target_price IS NULL OR target_price >= $3)
+		AND user_id > $4
+		ORDER BY user_id
+		LIMIT $5
+	`
+
+	rows, err := r.db.Query(ctx, query, productID, kind, price, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product watchers: %w", err)
+	}
+	defer rows.Close()
+
+	var users []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan product watcher: %w", err)
+		}
+		users = append(users, id)
+	}
+
+	return users, rows.Err()
+}
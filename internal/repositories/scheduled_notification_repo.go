@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/entities"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// ScheduledNotificationRepository persists notifications that are sent later
+type ScheduledNotificationRepository struct {
+	jobQueue
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewScheduledNotificationRepository(db *pgxpool.Pool, log *logrus.Logger) *ScheduledNotificationRepository {
+	return &ScheduledNotificationRepository{
+		jobQueue: jobQueue{db: db, table: "scheduled_notifications", dueColumn: "send_at"},
+		db:       db,
+		log:      log,
+	}
+}
+
+// Create inserts a pending scheduled notification
+func (r *ScheduledNotificationRepository) Create(ctx context.Context, n *entities.ScheduledNotification) error {
+	query := `
+		INSERT INTO scheduled_notifications (id, user_id, send_at, request, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, 'pending', NOW(), NOW())
+	`
+
+	if _, err := r.db.Exec(ctx, query, n.ID, n.UserID, n.SendAt, []byte(n.Request)); err != nil {
+		return fmt.Errorf("failed to insert scheduled notification: %w", err)
+	}
+
+	r.log.WithFields(logrus.Fields{
+		"scheduled_id": n.ID,
+		"send_at":      n.SendAt,
+	}).Debug("Scheduled notification created")
+	return nil
+}
+
+// Cancel cancels a pending scheduled notification
+func (r *ScheduledNotificationRepository) Cancel(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE scheduled_notifications
+		SET status = 'cancelled', updated_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+	`
+
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to cancel scheduled notification: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("scheduled notification not found")
+	}
+
+	return nil
+}
+
+// ClaimDue marks up to limit due notifications as processing and returns
+// them. Notifications stuck in processing for longer than staleAfter (e.g.
+// after a crash) are claimed again.
+func (r *ScheduledNotificationRepository) ClaimDue(ctx context.Context, limit int, staleAfter time.Duration) ([]entities.ScheduledNotification, error) {
+	rows, err := r.claimDue(ctx, limit, staleAfter, `
+		id, user_id, send_at, request, status,
+		attempts, COALESCE(last_error, ''), created_at, updated_at
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scheduled []entities.ScheduledNotification
+	for rows.Next() {
+		var n entities.ScheduledNotification
+
+		err := rows.Scan(
+			&n.ID,
+			&n.UserID,
+			&n.SendAt,
+			&n.Request,
+			&n.Status,
+			&n.Attempts,
+			&n.LastError,
+			&n.CreatedAt,
+			&n.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled notification: %w", err)
+		}
+
+		scheduled = append(scheduled, n)
+	}
+
+	return scheduled, rows.Err()
+}
@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/entities"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// WebhookRepository stores webhook subscriptions and their delivery health
+type WebhookRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewWebhookRepository(db *pgxpool.Pool, log *logrus.Logger) *WebhookRepository {
+	return &WebhookRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Subscribe registers an endpoint, or updates the secret and types of an
+// existing one. Re-registering re-enables a disabled endpoint.
+func (r *WebhookRepository) Subscribe(ctx context.Context, userID uuid.UUID, url, secret string, types []string) error {
+	if types == nil {
+		types = []string{}
+	}
+
+	query := `
+		INSERT INTO webhook_subscriptions (user_id, url, secret, types, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		ON CONFLICT (user_id, url) DO UPDATE
+		SET secret = EXCLUDED.secret,
+		    types = EXCLUDED.types,
+		    active = TRUE,
+		    consecutive_failures = 0,
+		    disabled_at = NULL,
+		    updated_at = NOW()
+	`
+
+	if _, err := r.db.Exec(ctx, query, userID, url, secret, types); err != nil {
+		return fmt.Errorf("failed to insert webhook subscription: %w", err)
+	}
+	return nil
+}
+
+// Unsubscribe removes an endpoint
+func (r *WebhookRepository) Unsubscribe(ctx context.Context, userID uuid.UUID, url string) error {
+	query := `DELETE FROM webhook_subscriptions WHERE user_id = $1 AND url = $2`
+
+	if _, err := r.db.Exec(ctx, query, userID, url); err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	return nil
+}
+
+// ListActive returns the user's enabled endpoints that accept notifType
+func (r *WebhookRepository) ListActive(ctx context.Context, userID uuid.UUID, notifType string) ([]entities.WebhookSubscription, error) {
+	query := `
+		SELECT id, user_id, url, secret, types, active, consecutive_failures,
+		       COALESCE(last_error, ''), disabled_at, created_at, updated_at
+		FROM webhook_subscriptions
+		WHERE user_id = $1 AND active = TRUE
+		AND (cardinality(types) = 0 OR $2 = ANY(types))
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, notifType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []entities.WebhookSubscription
+	for rows.Next() {
+		var sub entities.WebhookSubscription
+		err := rows.Scan(
+			&sub.ID,
+			&sub.UserID,
+			&sub.URL,
+			&sub.Secret,
+			&sub.Types,
+			&sub.Active,
+			&sub.ConsecutiveFailures,
+			&sub.LastError,
+			&sub.DisabledAt,
+			&sub.CreatedAt,
+			&sub.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook subscription: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+
+	return subs, rows.Err()
+}
+
+// RecordSuccess resets the failure count of an endpoint
+func (r *WebhookRepository) RecordSuccess(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE webhook_subscriptions
+		SET consecutive_failures = 0, last_error = NULL, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(ctx, query, id)
+	return err
+}
+
+// RecordFailure counts a failed delivery and disables the endpoint once it
+// has failed disableAfter times in a row. It reports whether the endpoint
+// was disabled.
+func (r *WebhookRepository) RecordFailure(ctx context.Context, id uuid.UUID, lastErr string, disableAfter int) (bool, error) {
+	query := `
+		UPDATE webhook_subscriptions
+		SET consecutive_failures = consecutive_failures + 1,
+		    last_error = $2,
+		    active = consecutive_failures + 1 < $3,
+		    disabled_at = CASE WHEN consecutive_failures + 1 >= $3 THEN NOW() ELSE disabled_at END,
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING active
+	`
+
+	var active bool
+	if err := r.db.QueryRow(ctx, query, id, lastErr, disableAfter).Scan(&active); err != nil {
+		return false, fmt.Errorf("failed to record webhook failure: %w", err)
+	}
+	return !active, nil
+}
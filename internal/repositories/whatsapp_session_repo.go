@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// WhatsAppSessionRepository tracks the last inbound WhatsApp message per
+// phone number, which opens the 24-hour customer service window
+type WhatsAppSessionRepository struct {
+	db  *pgxpool.Pool
+	log *logrus.Logger
+}
+
+func NewWhatsAppSessionRepository(db *pgxpool.Pool, log *logrus.Logger) *WhatsAppSessionRepository {
+	return &WhatsAppSessionRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Touch records an inbound message; older timestamps never overwrite newer ones
+func (r *WhatsAppSessionRepository) Touch(ctx context.Context, phoneNumber string, receivedAt time.Time) error {
+	query := `
+		INSERT INTO whatsapp_sessions (phone_number, last_inbound_at, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (phone_number) DO UPDATE
+		SET last_inbound_at = GREATEST(whatsapp_sessions.last_inbound_at, EXCLUDED.last_inbound_at),
+		    updated_at = NOW()
+	`
+
+	if _, err := r.db.Exec(ctx, query, phoneNumber, receivedAt); err != nil {
+		return fmt.Errorf("failed to update whatsapp session: %w", err)
+	}
+	return nil
+}
+
+// LastInbound returns the time of the last inbound message, or the zero
+// time if the number never wrote to us
+func (r *WhatsAppSessionRepository) LastInbound(ctx context.Context, phoneNumber string) (time.Time, error) {
+	query := `SELECT last_inbound_at FROM whatsapp_sessions WHERE phone_number = $1`
+
+	var lastInbound time.Time
+	err := r.db.QueryRow(ctx, query, phoneNumber).Scan(&lastInbound)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to query whatsapp session: %w", err)
+	}
+
+	return lastInbound, nil
+}
@@ -0,0 +1,359 @@
+package routing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/services"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/templates"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Notifier creates and delivers a notification, or posts an ops alert
+type Notifier interface {
+	CreateAndSendNotification(ctx context.Context, req *services.CreateNotificationRequest) error
+	SendOpsAlert(ctx context.Context, req *services.CreateNotificationRequest) error
+}
+
+// Engine turns events into notifications according to a rule set
+type Engine struct {
+	rules    map[string][]Rule
+	notifier Notifier
+	log      *logrus.Logger
+}
+
+// NewEngine registers the rule set's templates and validates every rule
+// against the schema of its event type. schemas holds the event types known
+// from Go structs; the rule set may declare additional ones.
+func NewEngine(ruleSet *RuleSet, reg *templates.Registry, schemas map[string]templates.Schema, notifier Notifier, log *logrus.Logger) (*Engine, error) {
+	allSchemas := make(map[string]templates.Schema, len(schemas)+len(ruleSet.Schemas))
+	for eventType, schema := range schemas {
+		allSchemas[eventType] = schema
+	}
+	for eventType, fields := range ruleSet.Schemas {
+		if _, exists := allSchemas[eventType]; exists {
+			return nil, fmt.Errorf("schema for %q is already declared by its event struct", eventType)
+		}
+		allSchemas[eventType] = templates.SchemaFromFields(fields)
+	}
+
+	for _, tc := range ruleSet.Templates {
+		schema, ok := allSchemas[tc.EventType]
+		if !ok {
+			return nil, fmt.Errorf("template %q: unknown event type %q", tc.Name, tc.EventType)
+		}
+		if err := reg.RegisterWithSchema(tc.Template, schema); err != nil {
+			return nil, err
+		}
+	}
+
+	for eventType, fields := range ruleSet.Metadata {
+		schema, ok := allSchemas[eventType]
+		if !ok {
+			return nil, fmt.Errorf("metadata: unknown event type %q", eventType)
+		}
+		for _, field := range fields {
+			if !schema[field] {
+				return nil, fmt.Errorf("metadata of %q: field %q not declared by event", eventType, field)
+			}
+		}
+	}
+
+	rules := make(map[string][]Rule)
+	for _, rule := range ruleSet.Rules {
+		schema, ok := allSchemas[rule.EventType]
+		if !ok {
+			return nil, fmt.Errorf("rule %q: unknown event type %q", rule.Name, rule.EventType)
+		}
+		if rule.Metadata == nil {
+			// Not nil even without a list, so that no metadata is stored
+			rule.Metadata = append([]string{}, ruleSet.Metadata[rule.EventType]...)
+		}
+		if err := rule.validate(schema, reg); err != nil {
+			return nil, err
+		}
+		rules[rule.EventType] = append(rules[rule.EventType], rule)
+	}
+
+	return &Engine{
+		rules:    rules,
+		notifier: notifier,
+		log:      log,
+	}, nil
+}
+
+// LoadRuleSet reads a rule set from path, or parses fallback when path is empty
+func LoadRuleSet(path string, fallback []byte) (*RuleSet, error) {
+	data := fallback
+	if path != "" {
+		fileData, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rules file: %w", err)
+		}
+		data = fileData
+	}
+
+	var ruleSet RuleSet
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&ruleSet); err != nil {
+		return nil, fmt.Errorf("failed to parse rules: %w", err)
+	}
+
+	return &ruleSet, nil
+}
+
+// EventTypes returns the event types that have at least one rule
+func (e *Engine) EventTypes() []string {
+	types := make([]string, 0, len(e.rules))
+	for eventType := range e.rules {
+		types = append(types, eventType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Handles reports whether any rule exists for the event type
+func (e *Engine) Handles(eventType string) bool {
+	return len(e.rules[eventType]) > 0
+}
+
+// dispatchResult counts what the rules matching one event delivered
+type dispatchResult struct {
+	delivered int
+	errs      []error
+}
+
+// Dispatch sends a notification for every rule matching the event. A rule
+// or recipient that fails is logged and does not stop the others. As a
+// redelivered event is sent to every recipient again, an error is only
+// returned when notifications failed and none was delivered. Ops alerts
+// never fail the event.
+func (e *Engine) Dispatch(ctx context.Context, eventType string, body []byte) error {
+	var data map[string]interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return fmt.Errorf("failed to unmarshal %s event: %w", eventType, err)
+	}
+	delete(data, "type")
+
+	var result dispatchResult
+	matched := 0
+
+	for i := range e.rules[eventType] {
+		rule := &e.rules[eventType][i]
+		if !rule.matches(data) {
+			continue
+		}
+		matched++
+
+		if err := e.apply(ctx, rule, data, &result); err != nil {
+			e.fail(&result, rule, "", err)
+		}
+	}
+
+	if matched == 0 {
+		e.log.WithField("event_type", eventType).Debug("No routing rule matched event")
+	}
+
+	if len(result.errs) == 0 {
+		return nil
+	}
+	if result.delivered == 0 {
+		return errors.Join(result.errs...)
+	}
+
+	e.log.WithFields(logrus.Fields{
+		"event_type": eventType,
+		"delivered":  result.delivered,
+		"failed":     len(result.errs),
+	}).Warn("Some routing rule notifications failed, not redelivering event")
+	return nil
+}
+
+// fail logs a failed rule, or a failed recipient of a rule, and adds it to
+// the result
+func (e *Engine) fail(result *dispatchResult, rule *Rule, userID string, err error) {
+	log := e.log.WithError(err).WithFields(logrus.Fields{
+		"rule":       rule.Name,
+		"event_type": rule.EventType,
+	})
+	if userID != "" {
+		log = log.WithField("user_id", userID)
+		err = fmt.Errorf("recipient %s: %w", userID, err)
+	}
+	log.Error("Routing rule notification failed")
+
+	result.errs = append(result.errs, fmt.Errorf("rule %q: %w", rule.Name, err))
+}
+
+// apply sends the rule's notification to each of its recipients, adding
+// their outcomes to result. An error means the rule could not be applied
+// at all.
+func (e *Engine) apply(ctx context.Context, rule *Rule, data map[string]interface{}, result *dispatchResult) error {
+	recipients := recipientIDs(data, rule.recipient())
+	if len(recipients) == 0 && rule.Audience != AudienceOps {
+		e.log.WithFields(logrus.Fields{
+			"rule":      rule.Name,
+			"recipient": rule.recipient(),
+		}).Warn("Event has no recipient, skipping rule")
+		return nil
+	}
+
+	var action *templates.EmailAction
+	if rule.Action != nil {
+		label, err := templates.Interpolate(rule.Name, rule.Action.Label, data, nil)
+		if err != nil {
+			return err
+		}
+		url, err := templates.Interpolate(rule.Name, rule.Action.URL, data, nil)
+		if err != nil {
+			return err
+		}
+		action = &templates.EmailAction{Label: label, URL: url}
+	}
+
+	var summary []templates.EmailSummaryRow
+	for _, row := range rule.Summary {
+		label, err := templates.Interpolate(rule.Name, row.Label, data, nil)
+		if err != nil {
+			return err
+		}
+		value, err := templates.Interpolate(rule.Name, row.Value, data, nil)
+		if err != nil {
+			return err
+		}
+		summary = append(summary, templates.EmailSummaryRow{Label: label, Value: value})
+	}
+
+	collapseKey, err := templates.Interpolate(rule.Name, rule.CollapseKey, data, nil)
+	if err != nil {
+		return err
+	}
+
+	if rule.Audience == AudienceOps {
+		e.log.WithFields(logrus.Fields{
+			"rule":       rule.Name,
+			"event_type": rule.EventType,
+		}).Info("Routing rule matched, alerting ops")
+
+		err := e.notifier.SendOpsAlert(ctx, &services.CreateNotificationRequest{
+			Type:     rule.Type,
+			Category: rule.Category,
+			Template: rule.Template,
+			Channels: rule.Channels,
+			Priority: rule.Priority,
+			Metadata: data,
+			Action:   action,
+			Summary:  summary,
+			Redact:   rule.Redact,
+
+			MetadataFields: rule.Metadata,
+		})
+		if err != nil {
+			// Failing the event over an ops chat outage would resend the
+			// customer and seller notifications of the same event
+			e.log.WithError(err).WithFields(logrus.Fields{
+				"rule":       rule.Name,
+				"event_type": rule.EventType,
+			}).Error("Failed to send ops alert")
+		}
+		return nil
+	}
+
+	var sendAt time.Time
+	if rule.delay > 0 {
+		sendAt = time.Now().Add(rule.delay)
+	}
+
+	for _, userID := range recipients {
+		log := e.log.WithFields(logrus.Fields{
+			"rule":       rule.Name,
+			"event_type": rule.EventType,
+			"user_id":    userID,
+		})
+
+		// Redelivering the event would not fix a malformed ID
+		if _, err := uuid.Parse(userID); err != nil {
+			log.WithError(err).Warn("Invalid recipient ID, skipping recipient")
+			continue
+		}
+		log.Info("Routing rule matched")
+
+		err := e.notifier.CreateAndSendNotification(ctx, &services.CreateNotificationRequest{
+			UserID:   userID,
+			Type:     rule.Type,
+			Category: rule.Category,
+			Template: rule.Template,
+			Channels: rule.Channels,
+			Priority: rule.Priority,
+			Metadata: data,
+			Action:   action,
+			Summary:  summary,
+			SendAt:   sendAt,
+
+			CollapseKey: collapseKey,
+			EventTime:   eventTime(data, rule.EventTime),
+			Redact:      rule.Redact,
+
+			MetadataFields: rule.Metadata,
+		})
+		if err != nil {
+			e.fail(result, rule, userID, err)
+			continue
+		}
+		result.delivered++
+	}
+
+	return nil
+}
+
+// eventTime reads the RFC 3339 time held by field, or returns the zero time
+// when the field is not set, missing or not a time
+func eventTime(data map[string]interface{}, field string) time.Time {
+	if field == "" {
+		return time.Time{}
+	}
+	value, _ := lookup(data, field)
+	text, ok := value.(string)
+	if !ok {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339Nano, text)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// recipientIDs reads the user IDs held by field, which may be a single ID or
+// a list of IDs. Empty and duplicate IDs are dropped.
+func recipientIDs(data map[string]interface{}, field string) []string {
+	value, _ := lookup(data, field)
+
+	var candidates []interface{}
+	switch v := value.(type) {
+	case string:
+		candidates = []interface{}{v}
+	case []interface{}:
+		candidates = v
+	}
+
+	seen := make(map[string]bool, len(candidates))
+	ids := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		id, ok := candidate.(string)
+		if !ok || id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -0,0 +1,241 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/services"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/templates"
+	"github.com/sirupsen/logrus"
+)
+
+func TestEventTime(t *testing.T) {
+	data := map[string]interface{}{
+		"updated_at": "2025-03-01T10:15:30.5+07:00",
+		"zero_at":    "0001-01-01T00:00:00Z",
+		"date_only":  "2025-03-01",
+		"count":      3.0,
+	}
+
+	tests := []struct {
+		field string
+		want  time.Time
+	}{
+		{field: "updated_at", want: time.Date(2025, 3, 1, 3, 15, 30, 500_000_000, time.UTC)},
+		{field: ""},
+		{field: "missing_at"},
+		{field: "zero_at"},
+		{field: "date_only"},
+		{field: "count"},
+	}
+
+	for _, tt := range tests {
+		if got := eventTime(data, tt.field); !got.Equal(tt.want) {
+			t.Errorf("eventTime(%q) = %v, want %v", tt.field, got, tt.want)
+		}
+	}
+}
+
+// fakeNotifier records requests and fails those for the users in fail
+type fakeNotifier struct {
+	requests []*services.CreateNotificationRequest
+	fail     map[string]bool
+}
+
+func (n *fakeNotifier) CreateAndSendNotification(ctx context.Context, req *services.CreateNotificationRequest) error {
+	n.requests = append(n.requests, req)
+	if n.fail[req.UserID] {
+		return errors.New("send failed")
+	}
+	return nil
+}
+
+func (n *fakeNotifier) SendOpsAlert(ctx context.Context, req *services.CreateNotificationRequest) error {
+	n.requests = append(n.requests, req)
+	return nil
+}
+
+const testRuleSet = `{
+	"schemas": {"order.paid": ["order_id", "user_id", "seller_ids", "paid_amount"]},
+	"templates": [{
+		"name": "seller_order_paid",
+		"event_type": "order.paid",
+		"title": "Pesanan {{order_id}} dibayar",
+		"message": "Pembayaran {{paid_amount}} diterima"
+	}],
+	"rules": [{
+		"name": "seller_order_paid",
+		"event_type": "order.paid",
+		"recipient": "seller_ids",
+		"template": "seller_order_paid",
+		"type": "order",
+		"category": "order_paid",
+		"channels": ["in_app"]
+	}]
+}`
+
+func loadTestRuleSet(t *testing.T) *RuleSet {
+	t.Helper()
+
+	ruleSet, err := LoadRuleSet("", []byte(testRuleSet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ruleSet
+}
+
+func newTestEngine(t *testing.T, notifier Notifier) *Engine {
+	t.Helper()
+
+	engine, err := newEngine(loadTestRuleSet(t), notifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+func newEngine(ruleSet *RuleSet, notifier Notifier) (*Engine, error) {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return NewEngine(ruleSet, templates.NewRegistry(), nil, notifier, log)
+}
+
+func TestEngineDispatchRecipients(t *testing.T) {
+	sellerA := "7f1c2d3e-0000-4000-8000-00000000000a"
+	sellerB := "7f1c2d3e-0000-4000-8000-00000000000b"
+
+	tests := []struct {
+		name      string
+		sellerIDs string
+		fail      []string
+		wantSent  []string
+		wantErr   bool
+	}{
+		{
+			name:      "every seller",
+			sellerIDs: `["` + sellerA + `", "` + sellerB + `", "` + sellerA + `"]`,
+			wantSent:  []string{sellerA, sellerB},
+		},
+		{
+			name:      "malformed IDs are skipped",
+			sellerIDs: `["not-a-uuid", "` + sellerB + `", 42]`,
+			wantSent:  []string{sellerB},
+		},
+		{
+			name:      "no valid recipient",
+			sellerIDs: `["not-a-uuid"]`,
+		},
+		{
+			name:      "a failed recipient does not fail the event once another was notified",
+			sellerIDs: `["` + sellerA + `", "` + sellerB + `"]`,
+			fail:      []string{sellerA},
+			wantSent:  []string{sellerA, sellerB},
+		},
+		{
+			name:      "every recipient failed",
+			sellerIDs: `["` + sellerA + `"]`,
+			fail:      []string{sellerA},
+			wantSent:  []string{sellerA},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier := &fakeNotifier{fail: make(map[string]bool)}
+			for _, id := range tt.fail {
+				notifier.fail[id] = true
+			}
+
+			body := `{"type": "order.paid", "order_id": "ORD-1", "paid_amount": 150000, "seller_ids": ` + tt.sellerIDs + `}`
+			err := newTestEngine(t, notifier).Dispatch(context.Background(), "order.paid", []byte(body))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Dispatch() error = %v, want error %v", err, tt.wantErr)
+			}
+
+			var sent []string
+			for _, req := range notifier.requests {
+				sent = append(sent, req.UserID)
+			}
+			if !reflect.DeepEqual(sent, tt.wantSent) {
+				t.Errorf("notified %v, want %v", sent, tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestEngineDispatchMetadata(t *testing.T) {
+	tests := []struct {
+		name         string
+		metadata     map[string][]string
+		ruleMetadata []string
+		want         []string
+		wantErr      bool
+	}{
+		{
+			name: "no list stores nothing",
+			want: []string{},
+		},
+		{
+			name:     "the event type's list",
+			metadata: map[string][]string{"order.paid": {"order_id", "paid_amount"}},
+			want:     []string{"order_id", "paid_amount"},
+		},
+		{
+			name:         "the rule's list overrides the event type's",
+			metadata:     map[string][]string{"order.paid": {"order_id", "paid_amount"}},
+			ruleMetadata: []string{"order_id"},
+			want:         []string{"order_id"},
+		},
+		{
+			name:         "undeclared rule field",
+			ruleMetadata: []string{"phone_number"},
+			wantErr:      true,
+		},
+		{
+			name:     "undeclared event type field",
+			metadata: map[string][]string{"order.paid": {"phone_number"}},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown event type",
+			metadata: map[string][]string{"order.lost": {"order_id"}},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ruleSet := loadTestRuleSet(t)
+			ruleSet.Metadata = tt.metadata
+			ruleSet.Rules[0].Metadata = tt.ruleMetadata
+
+			notifier := &fakeNotifier{}
+			engine, err := newEngine(ruleSet, notifier)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewEngine() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			body := `{"order_id": "ORD-1", "paid_amount": 150000, "seller_ids": ["7f1c2d3e-0000-4000-8000-00000000000a"]}`
+			if err := engine.Dispatch(context.Background(), "order.paid", []byte(body)); err != nil {
+				t.Fatal(err)
+			}
+
+			req := notifier.requests[0]
+			if !reflect.DeepEqual(req.MetadataFields, tt.want) {
+				t.Errorf("metadata fields = %v, want %v", req.MetadataFields, tt.want)
+			}
+			// Every field is still there to render the content
+			if req.Metadata["paid_amount"] == nil {
+				t.Errorf("metadata = %v, want the whole event", req.Metadata)
+			}
+		})
+	}
+}
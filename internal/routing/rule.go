@@ -0,0 +1,329 @@
+package routing
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/services"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/templates"
+)
+
+// RuleSet is the declarative routing configuration
+type RuleSet struct {
+	// Schemas declares the fields of event types that have no Go struct
+	Schemas map[string][]string `json:"schemas,omitempty"`
+
+	// Metadata lists, per event type, the event fields stored with and sent
+	// along the notifications of rules that set no metadata of their own.
+	// Event types without a list store none.
+	Metadata map[string][]string `json:"metadata,omitempty"`
+
+	Templates []TemplateConfig `json:"templates,omitempty"`
+	Rules     []Rule           `json:"rules"`
+}
+
+// TemplateConfig is a template defined in the rule set, validated against
+// the schema of EventType
+type TemplateConfig struct {
+	templates.Template
+	EventType string `json:"event_type"`
+}
+
+// Rule maps an event type, and optional field conditions, to a notification
+type Rule struct {
+	Name       string      `json:"name"`
+	EventType  string      `json:"event_type"`
+	Conditions []Condition `json:"conditions,omitempty"`
+
+	// Recipient is the event field holding the user ID to notify, or a list
+	// of user IDs (such as seller_ids) that are each notified
+	Recipient string `json:"recipient,omitempty"`
+
+	// Audience "ops" posts the notification to the internal ops chats
+	// instead of notifying a user; Recipient is then not used
+	Audience string `json:"audience,omitempty"`
+
+	Template string                      `json:"template"`
+	Type     string                      `json:"type"`
+	Category string                      `json:"category"`
+	Channels []string                    `json:"channels"`
+	Priority string                      `json:"priority,omitempty"`
+	Action   *templates.EmailAction      `json:"action,omitempty"`
+	Summary  []templates.EmailSummaryRow `json:"summary,omitempty"`
+
+	// Redact lists event fields used only for rendering, see
+	// services.CreateNotificationRequest
+	Redact []string `json:"redact,omitempty"`
+
+	// Metadata lists the event fields stored with the notification and
+	// passed to senders, overriding the rule set's list for the event type;
+	// the other fields are only used to render and address it
+	Metadata []string `json:"metadata,omitempty"`
+
+	// CollapseKey, e.g. "shipment:{{order_id}}", makes the notification
+	// replace the previous one with the same key instead of stacking
+	CollapseKey string `json:"collapse_key,omitempty"`
+
+	// EventTime is the event field holding when the event happened, e.g.
+	// "updated_at". A collapsing notification then never replaces one for a
+	// later event, as events may arrive out of order.
+	EventTime string `json:"event_time,omitempty"`
+
+	// Delay postpones the notification, e.g. "72h"
+	Delay string `json:"delay,omitempty"`
+	delay time.Duration
+}
+
+// Condition compares an event field against a value
+type Condition struct {
+	Field string      `json:"field"`
+	Op    string      `json:"op"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+const defaultRecipient = "user_id"
+
+// AudienceOps routes a rule to the internal ops chats
+const AudienceOps = "ops"
+
+// critical notifications bypass user preferences and quiet hours
+var priorities = map[string]bool{"low": true, "normal": true, "high": true, services.PriorityCritical: true}
+
+func (r *Rule) recipient() string {
+	if r.Recipient == "" {
+		return defaultRecipient
+	}
+	return r.Recipient
+}
+
+// texts returns every interpolated text of the rule apart from the template
+func (r *Rule) texts() []string {
+	var texts []string
+	if r.CollapseKey != "" {
+		texts = append(texts, r.CollapseKey)
+	}
+	if r.Action != nil {
+		texts = append(texts, r.Action.Label, r.Action.URL)
+	}
+	for _, row := range r.Summary {
+		texts = append(texts, row.Label, row.Value)
+	}
+	return texts
+}
+
+func (r *Rule) validate(schema templates.Schema, reg *templates.Registry) error {
+	if r.Name == "" {
+		return fmt.Errorf("rule for %q has no name", r.EventType)
+	}
+	if r.EventType == "" {
+		return fmt.Errorf("rule %q: event_type is required", r.Name)
+	}
+	if len(r.Channels) == 0 {
+		return fmt.Errorf("rule %q: at least one channel is required", r.Name)
+	}
+	if err := r.validateAudience(); err != nil {
+		return err
+	}
+	if r.Priority != "" && !priorities[r.Priority] {
+		return fmt.Errorf("rule %q: unknown priority %q", r.Name, r.Priority)
+	}
+	if r.Delay != "" {
+		delay, err := time.ParseDuration(r.Delay)
+		if err != nil || delay < 0 {
+			return fmt.Errorf("rule %q: invalid delay %q", r.Name, r.Delay)
+		}
+		r.delay = delay
+
+		// Delayed notifications are stored, which redacted fields must not be
+		if len(r.Redact) > 0 || r.Priority == services.PriorityCritical {
+			return fmt.Errorf("rule %q: delay cannot be used with redact or critical priority", r.Name)
+		}
+	}
+
+	tmpl, err := reg.Get(r.Template)
+	if err != nil {
+		return fmt.Errorf("rule %q: %w", r.Name, err)
+	}
+	if err := tmpl.Validate(schema); err != nil {
+		return fmt.Errorf("rule %q: %w", r.Name, err)
+	}
+	if err := templates.ValidateTexts(r.Name, schema, nil, r.texts()...); err != nil {
+		return fmt.Errorf("rule %q: %w", r.Name, err)
+	}
+
+	if r.EventTime != "" {
+		if r.CollapseKey == "" {
+			return fmt.Errorf("rule %q: event_time requires collapse_key", r.Name)
+		}
+		if !schema[r.EventTime] {
+			return fmt.Errorf("rule %q: event_time field %q not declared by event", r.Name, r.EventTime)
+		}
+	}
+
+	for _, field := range r.Redact {
+		if !schema[field] {
+			return fmt.Errorf("rule %q: redacted field %q not declared by event", r.Name, field)
+		}
+	}
+
+	for _, field := range r.Metadata {
+		if !schema[field] {
+			return fmt.Errorf("rule %q: metadata field %q not declared by event", r.Name, field)
+		}
+	}
+
+	if r.Audience != AudienceOps && !schema[r.recipient()] {
+		return fmt.Errorf("rule %q: recipient field %q not declared by event", r.Name, r.recipient())
+	}
+
+	for _, cond := range r.Conditions {
+		if !schema[rootField(cond.Field)] {
+			return fmt.Errorf("rule %q: condition field %q not declared by event", r.Name, cond.Field)
+		}
+		if _, ok := operators[cond.Op]; !ok {
+			return fmt.Errorf("rule %q: unknown condition operator %q", r.Name, cond.Op)
+		}
+	}
+
+	return nil
+}
+
+// validateAudience checks that ops rules only use ops channels and nothing
+// that needs a user, and that user rules do not use ops channels
+func (r *Rule) validateAudience() error {
+	switch r.Audience {
+	case "":
+		for _, channel := range r.Channels {
+			if services.IsOpsChannel(channel) {
+				return fmt.Errorf("rule %q: channel %q requires audience %q", r.Name, channel, AudienceOps)
+			}
+		}
+	case AudienceOps:
+		for _, channel := range r.Channels {
+			if !services.IsOpsChannel(channel) {
+				return fmt.Errorf("rule %q: channel %q cannot be used for audience %q", r.Name, channel, AudienceOps)
+			}
+		}
+		if r.Recipient != "" || r.CollapseKey != "" || r.Delay != "" {
+			return fmt.Errorf("rule %q: recipient, collapse_key and delay cannot be used for audience %q", r.Name, AudienceOps)
+		}
+	default:
+		return fmt.Errorf("rule %q: unknown audience %q", r.Name, r.Audience)
+	}
+	return nil
+}
+
+// matches reports whether every condition holds for the event data
+func (r *Rule) matches(data map[string]interface{}) bool {
+	for _, cond := range r.Conditions {
+		value, ok := lookup(data, cond.Field)
+		if !operators[cond.Op](value, ok, cond.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+var operators = map[string]func(value interface{}, present bool, expected interface{}) bool{
+	"exists": func(_ interface{}, present bool, _ interface{}) bool {
+		return present
+	},
+	"eq": func(value interface{}, present bool, expected interface{}) bool {
+		return present && equal(value, expected)
+	},
+	"ne": func(value interface{}, present bool, expected interface{}) bool {
+		return !present || !equal(value, expected)
+	},
+	"in": func(value interface{}, present bool, expected interface{}) bool {
+		return present && contains(expected, value)
+	},
+	"not_in": func(value interface{}, present bool, expected interface{}) bool {
+		return !present || !contains(expected, value)
+	},
+	"gt": func(value interface{}, present bool, expected interface{}) bool {
+		c, ok := compare(value, expected)
+		return present && ok && c > 0
+	},
+	"gte": func(value interface{}, present bool, expected interface{}) bool {
+		c, ok := compare(value, expected)
+		return present && ok && c >= 0
+	},
+	"lt": func(value interface{}, present bool, expected interface{}) bool {
+		c, ok := compare(value, expected)
+		return present && ok && c < 0
+	},
+	"lte": func(value interface{}, present bool, expected interface{}) bool {
+		c, ok := compare(value, expected)
+		return present && ok && c <= 0
+	},
+}
+
+// lookup resolves a dotted field path in decoded JSON
+func lookup(data map[string]interface{}, field string) (interface{}, bool) {
+	var current interface{} = data
+	for _, part := range strings.Split(field, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = m[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func rootField(field string) string {
+	return strings.SplitN(field, ".", 2)[0]
+}
+
+func equal(a, b interface{}) bool {
+	if c, ok := compare(a, b); ok {
+		return c == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func contains(list, value interface{}) bool {
+	items, ok := list.([]interface{})
+	if !ok {
+		return false
+	}
+	for _, item := range items {
+		if equal(value, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// compare orders two numbers or two strings
+func compare(a, b interface{}) (int, bool) {
+	if x, ok := a.(float64); ok {
+		y, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		default:
+			return 0, true
+		}
+	}
+
+	if x, ok := a.(string); ok {
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(x, y), true
+	}
+
+	return 0, false
+}
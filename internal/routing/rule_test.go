@@ -0,0 +1,213 @@
+package routing
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRuleMatches(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions string
+		data       string
+		want       bool
+	}{
+		{
+			name:       "no conditions",
+			conditions: `[]`,
+			data:       `{"order_id": "123"}`,
+			want:       true,
+		},
+		{
+			name:       "exists",
+			conditions: `[{"field": "tracking_number", "op": "exists"}]`,
+			data:       `{"tracking_number": "JNE123"}`,
+			want:       true,
+		},
+		{
+			name:       "exists with the field missing",
+			conditions: `[{"field": "tracking_number", "op": "exists"}]`,
+			data:       `{"order_id": "123"}`,
+			want:       false,
+		},
+		{
+			name:       "exists with a null value",
+			conditions: `[{"field": "tracking_number", "op": "exists"}]`,
+			data:       `{"tracking_number": null}`,
+			want:       true,
+		},
+		{
+			name:       "eq string",
+			conditions: `[{"field": "cancelled_by", "op": "eq", "value": "buyer"}]`,
+			data:       `{"cancelled_by": "buyer"}`,
+			want:       true,
+		},
+		{
+			name:       "eq string mismatch",
+			conditions: `[{"field": "cancelled_by", "op": "eq", "value": "buyer"}]`,
+			data:       `{"cancelled_by": "admin"}`,
+			want:       false,
+		},
+		{
+			name:       "eq with the field missing",
+			conditions: `[{"field": "cancelled_by", "op": "eq", "value": "buyer"}]`,
+			data:       `{}`,
+			want:       false,
+		},
+		{
+			name:       "eq number",
+			conditions: `[{"field": "item_count", "op": "eq", "value": 2}]`,
+			data:       `{"item_count": 2.0}`,
+			want:       true,
+		},
+		{
+			name:       "eq bool",
+			conditions: `[{"field": "is_preorder", "op": "eq", "value": true}]`,
+			data:       `{"is_preorder": true}`,
+			want:       true,
+		},
+		{
+			name:       "eq does not convert between types",
+			conditions: `[{"field": "item_count", "op": "eq", "value": "2"}]`,
+			data:       `{"item_count": 2}`,
+			want:       false,
+		},
+		{
+			name:       "ne",
+			conditions: `[{"field": "cancelled_by", "op": "ne", "value": "buyer"}]`,
+			data:       `{"cancelled_by": "system"}`,
+			want:       true,
+		},
+		{
+			name:       "ne with the field missing",
+			conditions: `[{"field": "cancelled_by", "op": "ne", "value": "buyer"}]`,
+			data:       `{}`,
+			want:       true,
+		},
+		{
+			name:       "in",
+			conditions: `[{"field": "cancelled_by", "op": "in", "value": ["admin", "system"]}]`,
+			data:       `{"cancelled_by": "system"}`,
+			want:       true,
+		},
+		{
+			name:       "in mismatch",
+			conditions: `[{"field": "cancelled_by", "op": "in", "value": ["admin", "system"]}]`,
+			data:       `{"cancelled_by": "seller"}`,
+			want:       false,
+		},
+		{
+			name:       "in with a non-list value",
+			conditions: `[{"field": "cancelled_by", "op": "in", "value": "admin"}]`,
+			data:       `{"cancelled_by": "admin"}`,
+			want:       false,
+		},
+		{
+			name:       "not_in",
+			conditions: `[{"field": "status", "op": "not_in", "value": ["delivered", "returned"]}]`,
+			data:       `{"status": "in_transit"}`,
+			want:       true,
+		},
+		{
+			name:       "not_in with the field missing",
+			conditions: `[{"field": "status", "op": "not_in", "value": ["delivered"]}]`,
+			data:       `{}`,
+			want:       true,
+		},
+		{
+			name:       "gte at the boundary",
+			conditions: `[{"field": "total_amount", "op": "gte", "value": 5000000}]`,
+			data:       `{"total_amount": 5000000}`,
+			want:       true,
+		},
+		{
+			name:       "gte below",
+			conditions: `[{"field": "total_amount", "op": "gte", "value": 5000000}]`,
+			data:       `{"total_amount": 4999999.99}`,
+			want:       false,
+		},
+		{
+			name:       "gt at the boundary",
+			conditions: `[{"field": "total_amount", "op": "gt", "value": 100}]`,
+			data:       `{"total_amount": 100}`,
+			want:       false,
+		},
+		{
+			name:       "lt",
+			conditions: `[{"field": "stock", "op": "lt", "value": 5}]`,
+			data:       `{"stock": 3}`,
+			want:       true,
+		},
+		{
+			name:       "lte at the boundary",
+			conditions: `[{"field": "stock", "op": "lte", "value": 5}]`,
+			data:       `{"stock": 5}`,
+			want:       true,
+		},
+		{
+			name:       "ordering compares strings",
+			conditions: `[{"field": "tier", "op": "gte", "value": "gold"}]`,
+			data:       `{"tier": "platinum"}`,
+			want:       true,
+		},
+		{
+			name:       "ordering a string against a number never matches",
+			conditions: `[{"field": "total_amount", "op": "gt", "value": 100}]`,
+			data:       `{"total_amount": "500"}`,
+			want:       false,
+		},
+		{
+			name:       "ordering with the field missing",
+			conditions: `[{"field": "total_amount", "op": "lt", "value": 100}]`,
+			data:       `{}`,
+			want:       false,
+		},
+		{
+			name:       "nested field",
+			conditions: `[{"field": "shipment.courier", "op": "eq", "value": "JNE"}]`,
+			data:       `{"shipment": {"courier": "JNE"}}`,
+			want:       true,
+		},
+		{
+			name:       "nested field through a non-object",
+			conditions: `[{"field": "shipment.courier", "op": "exists"}]`,
+			data:       `{"shipment": "JNE"}`,
+			want:       false,
+		},
+		{
+			name: "every condition must hold",
+			conditions: `[
+				{"field": "cancelled_by", "op": "eq", "value": "buyer"},
+				{"field": "total_amount", "op": "gte", "value": 5000000}
+			]`,
+			data: `{"cancelled_by": "buyer", "total_amount": 100000}`,
+			want: false,
+		},
+		{
+			name: "all conditions hold",
+			conditions: `[
+				{"field": "cancelled_by", "op": "eq", "value": "buyer"},
+				{"field": "total_amount", "op": "gte", "value": 5000000}
+			]`,
+			data: `{"cancelled_by": "buyer", "total_amount": 7500000}`,
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rule Rule
+			if err := json.Unmarshal([]byte(tt.conditions), &rule.Conditions); err != nil {
+				t.Fatalf("invalid conditions: %v", err)
+			}
+			var data map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.data), &data); err != nil {
+				t.Fatalf("invalid data: %v", err)
+			}
+
+			if got := rule.matches(data); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
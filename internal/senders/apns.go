@@ -0,0 +1,338 @@
+package senders
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/configs"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/entities"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// apnsMaxPayload is the largest alert payload APNs accepts
+const apnsMaxPayload = 4096
+
+// Provider tokens are renewed well within Apple's one hour validity, and
+// never more often than every 20 minutes as Apple requires
+const (
+	apnsTokenRefresh = 50 * time.Minute
+	apnsTokenMinAge  = 20 * time.Minute
+)
+
+// errAPNsTokenGone means APNs no longer accepts the device token
+var errAPNsTokenGone = errors.New("apns device token no longer valid")
+
+// UnreadCounter counts a user's unread notifications for the app badge
+type UnreadCounter interface {
+	GetUnreadCount(ctx context.Context, userID uuid.UUID) (int, error)
+}
+
+// APNsSender delivers push notifications to the recipient's iOS devices
+// directly through Apple's HTTP/2 API, using token-based (.p8) auth
+type APNsSender struct {
+	cfg     configs.APNsConfig
+	key     *ecdsa.PrivateKey
+	devices DeviceTokens
+	unread  UnreadCounter
+	client  *http.Client
+	log     *logrus.Logger
+
+	mu          sync.Mutex
+	token       string
+	tokenIssued time.Time
+}
+
+func NewAPNsSender(cfg configs.APNsConfig, devices DeviceTokens, unread UnreadCounter, log *logrus.Logger) (*APNsSender, error) {
+	if cfg.KeyID == "" || cfg.TeamID == "" || cfg.Topic == "" {
+		return nil, fmt.Errorf("apns key id, team id and topic are required")
+	}
+
+	key, err := loadAPNsKey(cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := url.Parse(cfg.BaseURL)
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("invalid apns base url %q", cfg.BaseURL)
+	}
+
+	// APNs only speaks HTTP/2; plain http base URLs (local stubs) use h2c
+	protocols := new(http.Protocols)
+	if base.Scheme == "http" {
+		protocols.SetUnencryptedHTTP2(true)
+	} else {
+		protocols.SetHTTP2(true)
+	}
+
+	return &APNsSender{
+		cfg:     cfg,
+		key:     key,
+		devices: devices,
+		unread:  unread,
+		client: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: &http.Transport{Protocols: protocols},
+		},
+		log: log,
+	}, nil
+}
+
+// loadAPNsKey reads the .p8 signing key downloaded from the Apple developer
+// account
+func loadAPNsKey(path string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read apns key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("apns key %s is not PEM encoded", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid apns key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("apns key is not an ECDSA key")
+	}
+	return key, nil
+}
+
+// apnsAlert is the body of an alert notification
+type apnsAlert struct {
+	APS struct {
+		Alert struct {
+			Title string `json:"title,omitempty"`
+			Body  string `json:"body"`
+		} `json:"alert"`
+		Badge    *int   `json:"badge,omitempty"`
+		Sound    string `json:"sound"`
+		ThreadID string `json:"thread-id,omitempty"`
+	} `json:"aps"`
+	NotificationID string                 `json:"notification_id,omitempty"`
+	Data           map[string]interface{} `json:"data,omitempty"`
+}
+
+func (s *APNsSender) Send(ctx context.Context, payload NotificationPayload) error {
+	userID, err := uuid.Parse(payload.To)
+	if err != nil {
+		return Permanent(fmt.Errorf("invalid apns recipient %q: %w", payload.To, err))
+	}
+
+	devices, err := s.devices.ListActive(ctx, userID, entities.PlatformAPNs)
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		s.log.WithField("user_id", payload.To).Debug("No APNs devices")
+		return ErrNoTarget
+	}
+
+	body, err := s.alertBody(ctx, userID, payload)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	// Devices are delivered concurrently, multiplexed over the HTTP/2
+	// connection
+	accepted := make([]bool, len(devices))
+	errs := ForEach(ctx, len(devices), deviceParallelism, func(i int) error {
+		device := devices[i]
+		err := s.deliver(ctx, device.Token, body, payload)
+		switch {
+		case errors.Is(err, errAPNsTokenGone):
+			s.log.WithFields(logrus.Fields{
+				"device_id": device.ID,
+				"reason":    err,
+			}).Info("APNs device token no longer valid, deactivating")
+			if err := s.devices.Deactivate(ctx, device.ID); err != nil {
+				s.log.WithError(err).Warn("Failed to deactivate APNs device token")
+			}
+			return nil
+		case err != nil:
+			s.log.WithError(err).WithField("device_id", device.ID).Warn("APNs push to device failed")
+			return fmt.Errorf("device %s: %w", device.ID, err)
+		default:
+			accepted[i] = true
+			if err := s.devices.MarkUsed(ctx, device.ID); err != nil {
+				s.log.WithError(err).Warn("Failed to record APNs delivery")
+			}
+			return nil
+		}
+	})
+
+	err = devicesResult(accepted, errs)
+	s.log.WithFields(logrus.Fields{
+		"type":    "APNS",
+		"to":      payload.To,
+		"devices": len(devices),
+		"failed":  countErrors(errs),
+	}).Info("APNs push sent")
+
+	return err
+}
+
+// alertBody builds the notification JSON, with the unread count as badge.
+// Data is dropped if the payload would exceed the APNs limit.
+func (s *APNsSender) alertBody(ctx context.Context, userID uuid.UUID, payload NotificationPayload) ([]byte, error) {
+	var alert apnsAlert
+	alert.APS.Alert.Title = payload.Subject
+	alert.APS.Alert.Body = payload.Body
+	alert.APS.Sound = "default"
+	alert.APS.ThreadID = payload.Type
+	alert.NotificationID = payload.NotificationID
+	alert.Data = payload.Data
+
+	if s.unread != nil {
+		count, err := s.unread.GetUnreadCount(ctx, userID)
+		if err != nil {
+			s.log.WithError(err).Warn("Failed to count unread notifications, sending without badge")
+		} else {
+			alert.APS.Badge = &count
+		}
+	}
+
+	body, err := json.Marshal(alert)
+	if err == nil && len(body) > apnsMaxPayload {
+		alert.Data = nil
+		body, err = json.Marshal(alert)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal apns payload: %w", err)
+	}
+	if len(body) > apnsMaxPayload {
+		return nil, fmt.Errorf("apns payload of %d bytes exceeds %d", len(body), apnsMaxPayload)
+	}
+	return body, nil
+}
+
+// apnsError is the body of a rejected request
+type apnsError struct {
+	Reason string `json:"reason"`
+}
+
+func (s *APNsSender) deliver(ctx context.Context, deviceToken string, body []byte, payload NotificationPayload) error {
+	token, err := s.providerToken(false)
+	if err != nil {
+		return err
+	}
+
+	endpoint := strings.TrimRight(s.cfg.BaseURL, "/") + "/3/device/" + url.PathEscape(deviceToken)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build apns request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "bearer "+token)
+	req.Header.Set("apns-topic", s.cfg.Topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", apnsPriority(payload.Priority))
+	if _, err := uuid.Parse(payload.NotificationID); err == nil {
+		req.Header.Set("apns-id", payload.NotificationID)
+	}
+	if payload.CollapseKey != "" {
+		req.Header.Set("apns-collapse-id", apnsCollapseID(payload.CollapseKey))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// The URL holds the device token
+		return fmt.Errorf("apns request failed: %w", stripURLError(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var rejection apnsError
+	_ = json.NewDecoder(resp.Body).Decode(&rejection)
+
+	switch {
+	case resp.StatusCode == http.StatusGone,
+		rejection.Reason == "BadDeviceToken",
+		rejection.Reason == "DeviceTokenNotForTopic":
+		return fmt.Errorf("%w: %s", errAPNsTokenGone, rejection.Reason)
+	case rejection.Reason == "ExpiredProviderToken":
+		// Sign a fresh token for the next attempt
+		if _, err := s.providerToken(true); err != nil {
+			s.log.WithError(err).Warn("Failed to renew APNs provider token")
+		}
+		return fmt.Errorf("apns returned %d: %s", resp.StatusCode, rejection.Reason)
+	}
+
+	err = fmt.Errorf("apns returned %d: %s", resp.StatusCode, rejection.Reason)
+	if rejectedStatus(resp.StatusCode) {
+		return Permanent(err)
+	}
+	return err
+}
+
+// providerToken returns the cached provider JWT, signing a new one when it
+// is due for renewal, or when renew is set and the token is old enough
+func (s *APNsSender) providerToken(renew bool) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	age := now.Sub(s.tokenIssued)
+	if s.token != "" && age < apnsTokenRefresh && !(renew && age >= apnsTokenMinAge) {
+		return s.token, nil
+	}
+
+	token, err := signES256(s.key,
+		map[string]interface{}{"alg": "ES256", "kid": s.cfg.KeyID},
+		map[string]interface{}{"iss": s.cfg.TeamID, "iat": now.Unix()},
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign apns provider token: %w", err)
+	}
+
+	s.token = token
+	s.tokenIssued = now
+	return token, nil
+}
+
+// apnsPriority sends low priority notifications power-efficiently
+func apnsPriority(priority string) string {
+	if priority == "low" {
+		return "5"
+	}
+	return "10"
+}
+
+// apnsCollapseID returns the collapse key, hashed if it exceeds the 64 byte
+// limit of apns-collapse-id
+func apnsCollapseID(collapseKey string) string {
+	if len(collapseKey) <= 64 {
+		return collapseKey
+	}
+	sum := sha256.Sum256([]byte(collapseKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *APNsSender) GetType() string {
+	return "push"
+}
+
+func (s *APNsSender) Name() string {
+	return "apns"
+}
@@ -0,0 +1,389 @@
+package senders
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/configs"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/entities"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeDevices is an in-memory DeviceTokens
+type fakeDevices struct {
+	mu          sync.Mutex
+	devices     []entities.DeviceToken
+	used        []uuid.UUID
+	deactivated []uuid.UUID
+}
+
+func (f *fakeDevices) ListActive(ctx context.Context, userID uuid.UUID, platform string) ([]entities.DeviceToken, error) {
+	var devices []entities.DeviceToken
+	for _, device := range f.devices {
+		if device.UserID == userID && device.Platform == platform {
+			devices = append(devices, device)
+		}
+	}
+	return devices, nil
+}
+
+func (f *fakeDevices) MarkUsed(ctx context.Context, id uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.used = append(f.used, id)
+	return nil
+}
+
+func (f *fakeDevices) Deactivate(ctx context.Context, id uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deactivated = append(f.deactivated, id)
+	return nil
+}
+
+type fixedUnread int
+
+func (n fixedUnread) GetUnreadCount(ctx context.Context, userID uuid.UUID) (int, error) {
+	return int(n), nil
+}
+
+// apnsRequest is what the stub server saw of one push
+type apnsRequest struct {
+	proto  int
+	token  string
+	header http.Header
+	body   []byte
+}
+
+// apnsStub is an h2c APNs server answering each device token with the
+// status and reason in responses (200 when missing)
+type apnsStub struct {
+	mu        sync.Mutex
+	requests  []apnsRequest
+	responses map[string]int
+}
+
+func (s *apnsStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	token := strings.TrimPrefix(r.URL.Path, "/3/device/")
+
+	s.mu.Lock()
+	s.requests = append(s.requests, apnsRequest{proto: r.ProtoMajor, token: token, header: r.Header.Clone(), body: body})
+	status, ok := s.responses[token]
+	s.mu.Unlock()
+
+	if !ok || status == http.StatusOK {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	reason := map[int]string{
+		http.StatusBadRequest:            "BadDeviceToken",
+		http.StatusGone:                  "Unregistered",
+		http.StatusRequestEntityTooLarge: "PayloadTooLarge",
+		http.StatusInternalServerError:   "InternalServerError",
+	}[status]
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apnsError{Reason: reason})
+}
+
+func newTestAPNs(t *testing.T, stub *apnsStub, devices *fakeDevices) (*APNsSender, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	server := httptest.NewUnstartedServer(stub)
+	server.Config.Protocols = protocols
+	server.Start()
+	t.Cleanup(server.Close)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "AuthKey.p8")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	sender, err := NewAPNsSender(configs.APNsConfig{
+		BaseURL: server.URL,
+		KeyFile: keyFile,
+		KeyID:   "KEY123",
+		TeamID:  "TEAM456",
+		Topic:   "com.tokohobby.app",
+		Timeout: 5 * time.Second,
+	}, devices, fixedUnread(3), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sender, key
+}
+
+func apnsDevices(userID uuid.UUID, tokens ...string) *fakeDevices {
+	devices := &fakeDevices{}
+	for _, token := range tokens {
+		devices.devices = append(devices.devices, entities.DeviceToken{
+			ID:       uuid.New(),
+			UserID:   userID,
+			Platform: entities.PlatformAPNs,
+			Token:    token,
+			Active:   true,
+		})
+	}
+	return devices
+}
+
+func TestAPNsSenderSend(t *testing.T) {
+	userID := uuid.New()
+	notificationID := uuid.NewString()
+	collapseKey := "order:" + strings.Repeat("x", 80)
+
+	stub := &apnsStub{}
+	devices := apnsDevices(userID, "token-a", "token-b")
+	sender, key := newTestAPNs(t, stub, devices)
+
+	err := sender.Send(context.Background(), NotificationPayload{
+		NotificationID: notificationID,
+		Type:           "order",
+		To:             userID.String(),
+		Subject:        "Pesanan Dikirim",
+		Body:           "Pesanan #123 sedang dikirim",
+		Priority:       "low",
+		CollapseKey:    collapseKey,
+		Data:           map[string]interface{}{"order_id": "123"},
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if len(stub.requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(stub.requests))
+	}
+	sum := sha256.Sum256([]byte(collapseKey))
+	for _, req := range stub.requests {
+		if req.proto != 2 {
+			t.Errorf("request used HTTP/%d, want HTTP/2", req.proto)
+		}
+		if req.token != "token-a" && req.token != "token-b" {
+			t.Errorf("unexpected device token %q", req.token)
+		}
+
+		wantHeaders := map[string]string{
+			"apns-topic":       "com.tokohobby.app",
+			"apns-push-type":   "alert",
+			"apns-priority":    "5",
+			"apns-id":          notificationID,
+			"apns-collapse-id": hex.EncodeToString(sum[:]),
+		}
+		for name, want := range wantHeaders {
+			if got := req.header.Get(name); got != want {
+				t.Errorf("%s = %q, want %q", name, got, want)
+			}
+		}
+		verifyProviderToken(t, req.header.Get("Authorization"), &key.PublicKey)
+
+		var alert apnsAlert
+		if err := json.Unmarshal(req.body, &alert); err != nil {
+			t.Fatalf("invalid body: %v", err)
+		}
+		if alert.APS.Alert.Title != "Pesanan Dikirim" || alert.APS.Alert.Body != "Pesanan #123 sedang dikirim" {
+			t.Errorf("alert = %+v", alert.APS.Alert)
+		}
+		if alert.APS.Badge == nil || *alert.APS.Badge != 3 {
+			t.Errorf("badge = %v, want 3", alert.APS.Badge)
+		}
+		if alert.APS.ThreadID != "order" || alert.Data["order_id"] != "123" {
+			t.Errorf("thread-id = %q, data = %v", alert.APS.ThreadID, alert.Data)
+		}
+	}
+
+	if len(devices.used) != 2 || len(devices.deactivated) != 0 {
+		t.Errorf("used %d, deactivated %d devices, want 2 and 0", len(devices.used), len(devices.deactivated))
+	}
+}
+
+func TestAPNsSenderSendResults(t *testing.T) {
+	tests := []struct {
+		name            string
+		responses       map[string]int
+		wantErr         bool
+		wantPartial     bool
+		wantRejected    bool
+		wantDeactivated int
+	}{
+		{
+			name:      "all accepted",
+			responses: map[string]int{},
+		},
+		{
+			name:            "gone token is deactivated, not failed",
+			responses:       map[string]int{"token-a": http.StatusGone},
+			wantDeactivated: 1,
+		},
+		{
+			name:            "bad device token is deactivated",
+			responses:       map[string]int{"token-b": http.StatusBadRequest},
+			wantDeactivated: 1,
+		},
+		{
+			name:        "one device failing is a partial delivery",
+			responses:   map[string]int{"token-a": http.StatusInternalServerError},
+			wantErr:     true,
+			wantPartial: true,
+		},
+		{
+			name: "every device failing fails the send",
+			responses: map[string]int{
+				"token-a": http.StatusInternalServerError,
+				"token-b": http.StatusInternalServerError,
+			},
+			wantErr: true,
+		},
+		{
+			name: "every device rejecting the push rejects the send",
+			responses: map[string]int{
+				"token-a": http.StatusRequestEntityTooLarge,
+				"token-b": http.StatusRequestEntityTooLarge,
+			},
+			wantErr:      true,
+			wantRejected: true,
+		},
+		{
+			name: "a rejection does not hide an outage",
+			responses: map[string]int{
+				"token-a": http.StatusRequestEntityTooLarge,
+				"token-b": http.StatusInternalServerError,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New()
+			devices := apnsDevices(userID, "token-a", "token-b")
+			sender, _ := newTestAPNs(t, &apnsStub{responses: tt.responses}, devices)
+
+			err := sender.Send(context.Background(), NotificationPayload{To: userID.String(), Body: "Halo"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if IsPartial(err) != tt.wantPartial {
+				t.Errorf("IsPartial() = %v, want %v", IsPartial(err), tt.wantPartial)
+			}
+			if IsPermanent(err) != tt.wantRejected {
+				t.Errorf("IsPermanent(%v) = %v, want %v", err, IsPermanent(err), tt.wantRejected)
+			}
+			if wantFailed := tt.wantErr && !tt.wantPartial && !tt.wantRejected; providerFailed(err) != wantFailed {
+				t.Errorf("providerFailed(%v) = %v, want %v", err, providerFailed(err), wantFailed)
+			}
+			if len(devices.deactivated) != tt.wantDeactivated {
+				t.Errorf("deactivated %d devices, want %d", len(devices.deactivated), tt.wantDeactivated)
+			}
+		})
+	}
+}
+
+func TestAPNsSenderSendWithoutDevices(t *testing.T) {
+	stub := &apnsStub{}
+	sender, _ := newTestAPNs(t, stub, &fakeDevices{})
+
+	err := sender.Send(context.Background(), NotificationPayload{To: uuid.NewString(), Body: "Halo"})
+	if !errors.Is(err, ErrNoTarget) {
+		t.Fatalf("Send() error = %v, want ErrNoTarget", err)
+	}
+	if len(stub.requests) != 0 {
+		t.Errorf("got %d requests, want none", len(stub.requests))
+	}
+}
+
+func TestAPNsSenderReusesProviderToken(t *testing.T) {
+	userID := uuid.New()
+	stub := &apnsStub{}
+	sender, _ := newTestAPNs(t, stub, apnsDevices(userID, "token-a"))
+
+	for i := 0; i < 2; i++ {
+		if err := sender.Send(context.Background(), NotificationPayload{To: userID.String(), Body: "Halo"}); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	first, second := stub.requests[0].header.Get("Authorization"), stub.requests[1].header.Get("Authorization")
+	if first == "" || first != second {
+		t.Errorf("provider token was not reused: %q, %q", first, second)
+	}
+}
+
+// verifyProviderToken checks the ES256 provider JWT in an Authorization
+// header against the signing key
+func verifyProviderToken(t *testing.T, authorization string, key *ecdsa.PublicKey) {
+	t.Helper()
+
+	token, ok := strings.CutPrefix(authorization, "bearer ")
+	if !ok {
+		t.Fatalf("Authorization = %q, want a bearer token", authorization)
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("provider token has %d parts, want 3", len(parts))
+	}
+
+	var header, claims map[string]interface{}
+	decodeSegment(t, parts[0], &header)
+	decodeSegment(t, parts[1], &claims)
+	if header["alg"] != "ES256" || header["kid"] != "KEY123" {
+		t.Errorf("token header = %v", header)
+	}
+	if claims["iss"] != "TEAM456" {
+		t.Errorf("token claims = %v", claims)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(signature) != 64 {
+		t.Fatalf("invalid token signature: %v", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(key, digest[:], r, s) {
+		t.Error("provider token signature does not verify")
+	}
+}
+
+func decodeSegment(t *testing.T, segment string, v interface{}) {
+	t.Helper()
+
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		t.Fatalf("invalid token segment: %v", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("invalid token segment: %v", err)
+	}
+}
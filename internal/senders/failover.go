@@ -0,0 +1,211 @@
+package senders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FailoverSender delivers a channel through an ordered list of providers,
+// moving on to the next one when a provider fails with a transient error.
+// Providers that keep failing are skipped until their cooldown has passed.
+type FailoverSender struct {
+	channel   string
+	providers []Sender
+	threshold int
+	cooldown  time.Duration
+	log       *logrus.Logger
+
+	mu     sync.Mutex
+	health []providerHealth
+}
+
+// providerHealth counts consecutive transient failures of a provider
+type providerHealth struct {
+	failures       int
+	unhealthyUntil time.Time
+}
+
+// NewFailoverSender builds a chain for channel from providers in order of
+// preference. A provider is considered unhealthy for cooldown after
+// threshold consecutive transient failures.
+func NewFailoverSender(channel string, providers []Sender, threshold int, cooldown time.Duration, log *logrus.Logger) (*FailoverSender, error) {
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("failover chain for %s has no providers", channel)
+	}
+	for _, provider := range providers {
+		if provider.GetType() != channel {
+			return nil, fmt.Errorf("provider %s delivers %s, not %s", ProviderName(provider), provider.GetType(), channel)
+		}
+	}
+
+	return &FailoverSender{
+		channel:   channel,
+		providers: providers,
+		threshold: threshold,
+		cooldown:  cooldown,
+		log:       log,
+		health:    make([]providerHealth, len(providers)),
+	}, nil
+}
+
+// ChainProviders groups providers into failover chains. For each channel in
+// chains, the providers named there are replaced by a single FailoverSender
+// trying them in that order. Names without a configured provider are
+// ignored and a chain of one provider is left unwrapped. Other providers are
+// returned as they are.
+func ChainProviders(providers []Sender, chains map[string][]string, threshold int, cooldown time.Duration, log *logrus.Logger) ([]Sender, error) {
+	members := make(map[string][]Sender)
+	chained := make(map[Sender]bool)
+	for channel, names := range chains {
+		for _, name := range names {
+			for _, provider := range providers {
+				if provider == nil || chained[provider] {
+					continue
+				}
+				if provider.GetType() == channel && ProviderName(provider) == name {
+					members[channel] = append(members[channel], provider)
+					chained[provider] = true
+					break
+				}
+			}
+		}
+	}
+
+	result := make([]Sender, 0, len(providers))
+	built := make(map[string]bool)
+	for _, provider := range providers {
+		if provider == nil {
+			continue
+		}
+
+		channel := provider.GetType()
+		chain := members[channel]
+		if !chained[provider] || len(chain) < 2 {
+			result = append(result, provider)
+			continue
+		}
+		if built[channel] {
+			continue
+		}
+
+		failover, err := NewFailoverSender(channel, chain, threshold, cooldown, log)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, failover)
+		built[channel] = true
+	}
+	return result, nil
+}
+
+func (s *FailoverSender) Send(ctx context.Context, payload NotificationPayload) error {
+	_, err := s.SendVia(ctx, payload)
+	return err
+}
+
+// SendVia sends through the first provider that succeeds and returns its
+// name. On failure it returns the last provider tried.
+func (s *FailoverSender) SendVia(ctx context.Context, payload NotificationPayload) (string, error) {
+	var errs []error
+	var provider string
+
+	for _, i := range s.order(time.Now()) {
+		p := s.providers[i]
+		provider = ProviderName(p)
+
+		err := p.Send(ctx, payload)
+		if !providerFailed(err) {
+			// Rejected notifications would be rejected by the next
+			// provider too
+			if err == nil || IsPartial(err) {
+				s.recordSuccess(i)
+			}
+			return provider, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", provider, err))
+
+		// A provider whose circuit is open was not tried, so it is not
+		// counted as failing
+		if _, deferred := DeferredUntil(err); deferred {
+			continue
+		}
+
+		// Running out of the caller's time says nothing about the provider
+		if ctx.Err() != nil {
+			break
+		}
+		s.recordFailure(i)
+
+		s.log.WithError(err).WithFields(logrus.Fields{
+			"channel":  s.channel,
+			"provider": provider,
+		}).Warn("Provider failed, failing over")
+	}
+
+	return provider, errors.Join(errs...)
+}
+
+// order returns the providers to try: healthy ones in chain order, then
+// unhealthy ones as a last resort
+func (s *FailoverSender) order(now time.Time) []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order := make([]int, 0, len(s.providers))
+	var unhealthy []int
+	for i, health := range s.health {
+		if now.Before(health.unhealthyUntil) {
+			unhealthy = append(unhealthy, i)
+			continue
+		}
+		order = append(order, i)
+	}
+	return append(order, unhealthy...)
+}
+
+func (s *FailoverSender) recordSuccess(i int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.health[i] = providerHealth{}
+}
+
+func (s *FailoverSender) recordFailure(i int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	health := &s.health[i]
+	health.failures++
+	if health.failures >= s.threshold {
+		health.unhealthyUntil = time.Now().Add(s.cooldown)
+		s.log.WithFields(logrus.Fields{
+			"channel":  s.channel,
+			"provider": ProviderName(s.providers[i]),
+			"failures": health.failures,
+			"until":    health.unhealthyUntil,
+		}).Warn("Provider marked unhealthy")
+	}
+}
+
+func (s *FailoverSender) GetType() string {
+	return s.channel
+}
+
+// viaSender is implemented by senders that choose between providers
+type viaSender interface {
+	SendVia(ctx context.Context, payload NotificationPayload) (string, error)
+}
+
+// SendVia sends through sender and returns the name of the provider that
+// delivered, or of the last one tried when it failed
+func SendVia(ctx context.Context, sender Sender, payload NotificationPayload) (string, error) {
+	if via, ok := sender.(viaSender); ok {
+		return via.SendVia(ctx, payload)
+	}
+	return ProviderName(sender), sender.Send(ctx, payload)
+}
@@ -0,0 +1,190 @@
+package senders
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fakeProvider is a named provider of a channel returning its errors in
+// turn, then nil
+type fakeProvider struct {
+	channel string
+	name    string
+	errs    []error
+	calls   int
+}
+
+func (p *fakeProvider) Send(ctx context.Context, payload NotificationPayload) error {
+	p.calls++
+	if len(p.errs) == 0 {
+		return nil
+	}
+	err := p.errs[0]
+	p.errs = p.errs[1:]
+	return err
+}
+
+func (p *fakeProvider) GetType() string { return p.channel }
+func (p *fakeProvider) Name() string    { return p.name }
+
+func quietLogger() *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return log
+}
+
+func TestFailoverSenderSendVia(t *testing.T) {
+	down := errors.New("relay unavailable")
+
+	tests := []struct {
+		name          string
+		primaryErrs   []error
+		fallbackErrs  []error
+		wantProvider  string
+		wantErr       bool
+		wantFallbacks int
+	}{
+		{
+			name:         "primary delivers",
+			wantProvider: "smtp",
+		},
+		{
+			name:          "transient failure fails over",
+			primaryErrs:   []error{down},
+			wantProvider:  "smtp_fallback",
+			wantFallbacks: 1,
+		},
+		{
+			name:         "rejected notification does not fail over",
+			primaryErrs:  []error{Permanent(down)},
+			wantProvider: "smtp",
+			wantErr:      true,
+		},
+		{
+			name:         "missing target does not fail over",
+			primaryErrs:  []error{ErrNoTarget},
+			wantProvider: "smtp",
+			wantErr:      true,
+		},
+		{
+			name:          "every provider fails",
+			primaryErrs:   []error{down},
+			fallbackErrs:  []error{down},
+			wantProvider:  "smtp_fallback",
+			wantErr:       true,
+			wantFallbacks: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &fakeProvider{channel: "email", name: "smtp", errs: tt.primaryErrs}
+			fallback := &fakeProvider{channel: "email", name: "smtp_fallback", errs: tt.fallbackErrs}
+
+			failover, err := NewFailoverSender("email", []Sender{primary, fallback}, 3, time.Minute, quietLogger())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			provider, err := failover.SendVia(context.Background(), NotificationPayload{})
+			if provider != tt.wantProvider {
+				t.Errorf("provider = %q, want %q", provider, tt.wantProvider)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SendVia() error = %v, want error %v", err, tt.wantErr)
+			}
+			if fallback.calls != tt.wantFallbacks {
+				t.Errorf("fallback called %d times, want %d", fallback.calls, tt.wantFallbacks)
+			}
+		})
+	}
+}
+
+func TestFailoverSenderSkipsUnhealthyProviders(t *testing.T) {
+	down := errors.New("relay unavailable")
+	primary := &fakeProvider{channel: "email", name: "smtp", errs: []error{down, down}}
+	fallback := &fakeProvider{channel: "email", name: "smtp_fallback"}
+
+	failover, err := NewFailoverSender("email", []Sender{primary, fallback}, 2, time.Minute, quietLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		failover.Send(context.Background(), NotificationPayload{})
+	}
+	if primary.calls != 2 {
+		t.Fatalf("primary called %d times, want 2", primary.calls)
+	}
+
+	provider, err := failover.SendVia(context.Background(), NotificationPayload{})
+	if err != nil || provider != "smtp_fallback" {
+		t.Errorf("SendVia() = %q, %v, want smtp_fallback", provider, err)
+	}
+	if primary.calls != 2 {
+		t.Errorf("unhealthy primary was tried first")
+	}
+}
+
+func TestFailoverSenderCancelledCallerDoesNotCount(t *testing.T) {
+	primary := &fakeProvider{channel: "email", name: "smtp", errs: []error{context.Canceled}}
+	fallback := &fakeProvider{channel: "email", name: "smtp_fallback"}
+
+	failover, err := NewFailoverSender("email", []Sender{primary, fallback}, 1, time.Minute, quietLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	failover.Send(ctx, NotificationPayload{})
+
+	if fallback.calls != 0 {
+		t.Errorf("failed over after the caller gave up")
+	}
+	if order := failover.order(time.Now()); order[0] != 0 {
+		t.Errorf("primary marked unhealthy after a cancelled send")
+	}
+}
+
+func TestChainProviders(t *testing.T) {
+	smtp := &fakeProvider{channel: "email", name: "smtp"}
+	smtpFallback := &fakeProvider{channel: "email", name: "smtp_fallback"}
+	sms := &fakeProvider{channel: "sms", name: "twilio"}
+	push := &fakeProvider{channel: "push", name: "fcm"}
+
+	chains := map[string][]string{
+		"email": {"smtp_fallback", "smtp"},
+		"sms":   {"twilio", "vonage"},
+	}
+
+	providers, err := ChainProviders([]Sender{smtp, nil, smtpFallback, sms, push}, chains, 3, time.Minute, quietLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(providers) != 3 {
+		t.Fatalf("got %d providers, want 3", len(providers))
+	}
+
+	failover, ok := providers[0].(*FailoverSender)
+	if !ok {
+		t.Fatalf("email provider is %T, want *FailoverSender", providers[0])
+	}
+	if len(failover.providers) != 2 || failover.providers[0] != smtpFallback || failover.providers[1] != smtp {
+		t.Errorf("email chain is not in the configured order")
+	}
+
+	// A chain with a single configured provider is left unwrapped
+	if providers[1] != sms {
+		t.Errorf("sms provider is %T, want the provider itself", providers[1])
+	}
+	if providers[2] != push {
+		t.Errorf("push provider is %T, want the provider itself", providers[2])
+	}
+}
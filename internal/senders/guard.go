@@ -0,0 +1,259 @@
+package senders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/configs"
+	"github.com/sirupsen/logrus"
+)
+
+// DeferredError is returned without contacting the provider while its
+// circuit is open. The delivery should be retried after RetryAt.
+type DeferredError struct {
+	Provider string
+	RetryAt  time.Time
+}
+
+func (e *DeferredError) Error() string {
+	return fmt.Sprintf("%s circuit open until %s", e.Provider, e.RetryAt.Format(time.RFC3339))
+}
+
+// DeferredUntil reports whether err defers the delivery, and until when
+func DeferredUntil(err error) (time.Time, bool) {
+	var deferred *DeferredError
+	if errors.As(err, &deferred) {
+		return deferred.RetryAt, true
+	}
+	return time.Time{}, false
+}
+
+// GuardedSender protects a provider: sends wait for a token of the
+// provider's rate limit, run under a timeout, and are deferred while the
+// circuit breaker is open after a burst of failures
+type GuardedSender struct {
+	sender  Sender
+	name    string
+	timeout time.Duration
+	limiter *tokenBucket
+	breaker *circuitBreaker
+	log     *logrus.Logger
+}
+
+// NewGuardedSender wraps sender with the limits cfg sets for its provider
+func NewGuardedSender(sender Sender, cfg configs.SenderGuardConfig, log *logrus.Logger) *GuardedSender {
+	name := ProviderName(sender)
+
+	rate := cfg.RateLimit
+	if limit, ok := cfg.RateLimits[name]; ok {
+		rate = limit
+	}
+
+	g := &GuardedSender{
+		sender:  sender,
+		name:    name,
+		timeout: cfg.Timeout,
+		breaker: &circuitBreaker{
+			threshold: cfg.BreakerThreshold,
+			window:    cfg.BreakerWindow,
+			openFor:   cfg.BreakerOpenFor,
+		},
+		log: log,
+	}
+	if rate > 0 {
+		g.limiter = newTokenBucket(float64(rate), max(cfg.Burst, 1))
+	}
+	return g
+}
+
+func (g *GuardedSender) Send(ctx context.Context, payload NotificationPayload) error {
+	if retryAt, ok := g.breaker.allow(time.Now()); !ok {
+		return &DeferredError{Provider: g.name, RetryAt: retryAt}
+	}
+
+	if g.limiter != nil {
+		if err := g.limiter.wait(ctx); err != nil {
+			g.breaker.release()
+			return fmt.Errorf("%s rate limit: %w", g.name, err)
+		}
+	}
+
+	sendCtx := ctx
+	if g.timeout > 0 {
+		var cancel context.CancelFunc
+		sendCtx, cancel = context.WithTimeout(ctx, g.timeout)
+		defer cancel()
+	}
+
+	err := g.sender.Send(sendCtx, payload)
+	switch {
+	case ctx.Err() != nil:
+		// Cancelled by the caller, which says nothing about the provider
+		g.breaker.release()
+	case providerFailed(err):
+		if opened, until := g.breaker.failure(time.Now()); opened {
+			g.log.WithError(err).WithFields(logrus.Fields{
+				"provider": g.name,
+				"until":    until,
+			}).Warn("Provider circuit opened")
+		}
+	default:
+		// Rejections and partial deliveries are answers from a working
+		// provider
+		if g.breaker.success() {
+			g.log.WithField("provider", g.name).Info("Provider circuit closed")
+		}
+	}
+	return err
+}
+
+func (g *GuardedSender) GetType() string {
+	return g.sender.GetType()
+}
+
+func (g *GuardedSender) Name() string {
+	return g.name
+}
+
+// tokenBucket allows rate sends per second with bursts of up to burst
+type tokenBucket struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait takes a token, blocking until one is available or ctx is done
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		delay := b.take(time.Now())
+		if delay == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// take removes a token and returns 0, or returns how long until the next
+// token is available
+func (b *tokenBucket) take(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// circuitBreaker opens after threshold failures within window. While open,
+// sends are refused; after openFor a single trial send is let through,
+// which closes the circuit on success and reopens it on failure.
+type circuitBreaker struct {
+	threshold int
+	window    time.Duration
+	openFor   time.Duration
+
+	mu        sync.Mutex
+	failures  []time.Time
+	openUntil time.Time
+	trial     bool
+}
+
+// allow reports whether a send may go ahead, or when to retry if not
+func (c *circuitBreaker) allow(now time.Time) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.openUntil.IsZero() {
+		return time.Time{}, true
+	}
+	if now.Before(c.openUntil) {
+		return c.openUntil, false
+	}
+	if c.trial {
+		// openUntil has passed while the trial send is in flight; if the
+		// trial fails the circuit stays open for another openFor
+		return now.Add(c.openFor), false
+	}
+	c.trial = true
+	return time.Time{}, true
+}
+
+// failure records a failed send and reports whether it opened the circuit
+func (c *circuitBreaker) failure(now time.Time) (bool, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.trial {
+		c.trial = false
+		c.openUntil = now.Add(c.openFor)
+		return true, c.openUntil
+	}
+	if c.threshold <= 0 {
+		return false, time.Time{}
+	}
+
+	// Only failures within the window count towards the burst
+	recent := c.failures[:0]
+	for _, at := range c.failures {
+		if now.Sub(at) < c.window {
+			recent = append(recent, at)
+		}
+	}
+	c.failures = append(recent, now)
+
+	if len(c.failures) < c.threshold {
+		return false, time.Time{}
+	}
+	c.failures = nil
+	c.openUntil = now.Add(c.openFor)
+	return true, c.openUntil
+}
+
+// success records a send the provider answered and reports whether it
+// closed the circuit
+func (c *circuitBreaker) success() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.trial {
+		return false
+	}
+	c.trial = false
+	c.openUntil = time.Time{}
+	return true
+}
+
+// release gives up a trial send that never reached the provider
+func (c *circuitBreaker) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.trial = false
+}
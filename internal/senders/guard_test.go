@@ -0,0 +1,273 @@
+package senders
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/configs"
+	"github.com/sirupsen/logrus"
+)
+
+// breakerStep is one call on a circuitBreaker, at an offset from the start
+type breakerStep struct {
+	at time.Duration
+	op string // allow, failure, success or release
+
+	// allow: whether the send may go ahead, else when to retry
+	wantAllowed bool
+	wantRetryAt time.Duration
+
+	// failure: whether it opened the circuit, and until when;
+	// success: whether it closed the circuit
+	wantChanged bool
+	wantUntil   time.Duration
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	const (
+		threshold = 3
+		window    = time.Minute
+		openFor   = 2 * time.Minute
+	)
+
+	tests := []struct {
+		name      string
+		threshold int
+		steps     []breakerStep
+	}{
+		{
+			name:      "stays closed below the threshold",
+			threshold: threshold,
+			steps: []breakerStep{
+				{at: 0, op: "failure"},
+				{at: time.Second, op: "failure"},
+				{at: 2 * time.Second, op: "allow", wantAllowed: true},
+			},
+		},
+		{
+			name:      "opens at the threshold",
+			threshold: threshold,
+			steps: []breakerStep{
+				{at: 0, op: "failure"},
+				{at: time.Second, op: "failure"},
+				{at: 2 * time.Second, op: "failure", wantChanged: true, wantUntil: 2*time.Second + openFor},
+				{at: 3 * time.Second, op: "allow", wantRetryAt: 2*time.Second + openFor},
+			},
+		},
+		{
+			name:      "failures outside the window do not count",
+			threshold: threshold,
+			steps: []breakerStep{
+				{at: 0, op: "failure"},
+				{at: time.Second, op: "failure"},
+				{at: window + 2*time.Second, op: "failure"},
+				{at: window + 3*time.Second, op: "failure"},
+				{at: window + 4*time.Second, op: "allow", wantAllowed: true},
+			},
+		},
+		{
+			name:      "successes outside a trial do not reset the count",
+			threshold: threshold,
+			steps: []breakerStep{
+				{at: 0, op: "failure"},
+				{at: time.Second, op: "failure"},
+				{at: 2 * time.Second, op: "success"},
+				{at: 3 * time.Second, op: "failure", wantChanged: true, wantUntil: 3*time.Second + openFor},
+			},
+		},
+		{
+			name:      "a single trial is let through once open time has passed",
+			threshold: 1,
+			steps: []breakerStep{
+				{at: 0, op: "failure", wantChanged: true, wantUntil: openFor},
+				{at: openFor, op: "allow", wantAllowed: true},
+				// Waiting on the trial defers by a full open period rather than
+				// to the open time that already passed
+				{at: openFor + time.Second, op: "allow", wantRetryAt: 2*openFor + time.Second},
+			},
+		},
+		{
+			name:      "a successful trial closes the circuit",
+			threshold: 1,
+			steps: []breakerStep{
+				{at: 0, op: "failure", wantChanged: true, wantUntil: openFor},
+				{at: openFor, op: "allow", wantAllowed: true},
+				{at: openFor + time.Second, op: "success", wantChanged: true},
+				{at: openFor + 2*time.Second, op: "allow", wantAllowed: true},
+				{at: openFor + 3*time.Second, op: "allow", wantAllowed: true},
+			},
+		},
+		{
+			name:      "a failed trial reopens the circuit",
+			threshold: threshold,
+			steps: []breakerStep{
+				{at: 0, op: "failure"},
+				{at: 0, op: "failure"},
+				{at: 0, op: "failure", wantChanged: true, wantUntil: openFor},
+				{at: openFor, op: "allow", wantAllowed: true},
+				{at: openFor + time.Second, op: "failure", wantChanged: true, wantUntil: 2*openFor + time.Second},
+				{at: openFor + 2*time.Second, op: "allow", wantRetryAt: 2*openFor + time.Second},
+			},
+		},
+		{
+			name:      "a released trial lets the next send try",
+			threshold: 1,
+			steps: []breakerStep{
+				{at: 0, op: "failure", wantChanged: true, wantUntil: openFor},
+				{at: openFor, op: "allow", wantAllowed: true},
+				{at: openFor, op: "release"},
+				{at: openFor + time.Second, op: "allow", wantAllowed: true},
+			},
+		},
+		{
+			name:      "a zero threshold never opens",
+			threshold: 0,
+			steps: []breakerStep{
+				{at: 0, op: "failure"},
+				{at: 0, op: "failure"},
+				{at: 0, op: "failure"},
+				{at: time.Second, op: "allow", wantAllowed: true},
+			},
+		},
+	}
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker := &circuitBreaker{threshold: tt.threshold, window: window, openFor: openFor}
+
+			for i, step := range tt.steps {
+				now := start.Add(step.at)
+
+				switch step.op {
+				case "allow":
+					retryAt, allowed := breaker.allow(now)
+					if allowed != step.wantAllowed {
+						t.Fatalf("step %d: allow() = %v, want %v", i, allowed, step.wantAllowed)
+					}
+					if !allowed && !retryAt.Equal(start.Add(step.wantRetryAt)) {
+						t.Errorf("step %d: retry at %v, want %v", i, retryAt.Sub(start), step.wantRetryAt)
+					}
+				case "failure":
+					opened, until := breaker.failure(now)
+					if opened != step.wantChanged {
+						t.Fatalf("step %d: failure() opened = %v, want %v", i, opened, step.wantChanged)
+					}
+					if opened && !until.Equal(start.Add(step.wantUntil)) {
+						t.Errorf("step %d: open until %v, want %v", i, until.Sub(start), step.wantUntil)
+					}
+				case "success":
+					if closed := breaker.success(); closed != step.wantChanged {
+						t.Fatalf("step %d: success() closed = %v, want %v", i, closed, step.wantChanged)
+					}
+				case "release":
+					breaker.release()
+				default:
+					t.Fatalf("step %d: unknown op %q", i, step.op)
+				}
+			}
+		})
+	}
+}
+
+// scriptedSender returns its errors in turn, then nil
+type scriptedSender struct {
+	errs  []error
+	calls int
+}
+
+func (s *scriptedSender) Send(ctx context.Context, payload NotificationPayload) error {
+	s.calls++
+	if len(s.errs) == 0 {
+		return nil
+	}
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	return err
+}
+
+func (s *scriptedSender) GetType() string { return "sms" }
+func (s *scriptedSender) Name() string    { return "sms_gateway" }
+
+func TestGuardedSenderBreaker(t *testing.T) {
+	down := errors.New("gateway unavailable")
+
+	tests := []struct {
+		name         string
+		errs         []error
+		wantDeferred bool
+	}{
+		{
+			name:         "transient failures open the circuit",
+			errs:         []error{down, down},
+			wantDeferred: true,
+		},
+		{
+			name: "rejected notifications do not count",
+			errs: []error{Permanent(down), Permanent(down)},
+		},
+		{
+			name: "partial deliveries do not count",
+			errs: []error{&PartialError{Delivered: 1, Err: down}, &PartialError{Delivered: 1, Err: down}},
+		},
+		{
+			name: "recipients without targets do not count",
+			errs: []error{ErrNoTarget, ErrNoTarget},
+		},
+	}
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &scriptedSender{errs: tt.errs}
+			guarded := NewGuardedSender(provider, configs.SenderGuardConfig{
+				BreakerThreshold: 2,
+				BreakerWindow:    time.Minute,
+				BreakerOpenFor:   time.Minute,
+			}, log)
+
+			for range tt.errs {
+				guarded.Send(context.Background(), NotificationPayload{})
+			}
+
+			err := guarded.Send(context.Background(), NotificationPayload{})
+			if _, deferred := DeferredUntil(err); deferred != tt.wantDeferred {
+				t.Errorf("third send error = %v, deferred = %v, want %v", err, deferred, tt.wantDeferred)
+			}
+			wantCalls := len(tt.errs)
+			if !tt.wantDeferred {
+				wantCalls++
+			}
+			if provider.calls != wantCalls {
+				t.Errorf("provider called %d times, want %d", provider.calls, wantCalls)
+			}
+		})
+	}
+}
+
+func TestGuardedSenderIgnoresCallerCancellation(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	provider := &scriptedSender{errs: []error{context.Canceled, context.Canceled}}
+	guarded := NewGuardedSender(provider, configs.SenderGuardConfig{
+		BreakerThreshold: 1,
+		BreakerWindow:    time.Minute,
+		BreakerOpenFor:   time.Minute,
+	}, log)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	guarded.Send(ctx, NotificationPayload{})
+
+	if err := guarded.Send(context.Background(), NotificationPayload{}); err != nil {
+		if _, deferred := DeferredUntil(err); deferred {
+			t.Fatalf("circuit opened after a cancelled send: %v", err)
+		}
+	}
+}
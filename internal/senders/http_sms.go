@@ -0,0 +1,119 @@
+package senders
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/configs"
+	"github.com/sirupsen/logrus"
+)
+
+// HTTPSMSSender sends SMS through a generic HTTP gateway whose request body
+// is built from a configurable template
+type HTTPSMSSender struct {
+	cfg    configs.SMSConfig
+	client *http.Client
+	log    *logrus.Logger
+}
+
+func NewHTTPSMSSender(cfg configs.SMSConfig, log *logrus.Logger) (*HTTPSMSSender, error) {
+	if cfg.GatewayURL == "" {
+		return nil, fmt.Errorf("sms gateway url is required")
+	}
+	if _, err := url.Parse(cfg.GatewayURL); err != nil {
+		return nil, fmt.Errorf("invalid sms gateway url: %w", err)
+	}
+
+	return &HTTPSMSSender{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		log:    log,
+	}, nil
+}
+
+func (s *HTTPSMSSender) Send(ctx context.Context, payload NotificationPayload) error {
+	to, err := NormalizePhoneE164(payload.To)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	message := FitSMS(payload.Body, s.cfg.MaxSegments)
+	info := SMSSegments(message)
+
+	body := s.renderBody(map[string]string{
+		"to":        to,
+		"to_digits": strings.TrimPrefix(to, "+"),
+		"message":   message,
+		"sender_id": s.cfg.SenderID,
+	})
+
+	req, err := http.NewRequestWithContext(ctx, s.cfg.Method, s.cfg.GatewayURL, strings.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build sms request: %w", err)
+	}
+	req.Header.Set("Content-Type", s.cfg.ContentType)
+	if s.cfg.AuthValue != "" {
+		req.Header.Set(s.cfg.AuthHeader, s.cfg.AuthValue)
+	}
+	if s.cfg.Username != "" {
+		req.SetBasicAuth(s.cfg.Username, s.cfg.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sms gateway request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := fmt.Errorf("sms gateway returned %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+		if rejectedStatus(resp.StatusCode) {
+			return Permanent(err)
+		}
+		return err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"type":     "SMS",
+		"to":       to,
+		"encoding": info.Encoding,
+		"segments": info.Segments,
+	}).Info("SMS sent")
+
+	return nil
+}
+
+// renderBody fills the body template, escaping values for the content type
+func (s *HTTPSMSSender) renderBody(values map[string]string) string {
+	pairs := make([]string, 0, len(values)*2)
+	for key, value := range values {
+		pairs = append(pairs, "{{"+key+"}}", s.escape(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(s.cfg.BodyTemplate)
+}
+
+func (s *HTTPSMSSender) escape(value string) string {
+	switch {
+	case strings.Contains(s.cfg.ContentType, "json"):
+		quoted, _ := json.Marshal(value)
+		return string(quoted[1 : len(quoted)-1])
+	case strings.Contains(s.cfg.ContentType, "x-www-form-urlencoded"):
+		return url.QueryEscape(value)
+	default:
+		return value
+	}
+}
+
+func (s *HTTPSMSSender) GetType() string {
+	return "sms"
+}
+
+func (s *HTTPSMSSender) Name() string {
+	return s.cfg.Name
+}
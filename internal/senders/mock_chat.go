@@ -0,0 +1,42 @@
+package senders
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MockChatSender simulates posting ops alerts to a chat channel for demo
+type MockChatSender struct {
+	channel string
+	log     *logrus.Logger
+}
+
+func NewMockChatSender(channel string, log *logrus.Logger) *MockChatSender {
+	return &MockChatSender{channel: channel, log: log}
+}
+
+func (s *MockChatSender) Send(ctx context.Context, payload NotificationPayload) error {
+	// Simulate API delay
+	if err := simulateDelay(ctx, 50*time.Millisecond); err != nil {
+		return err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"type":    strings.ToUpper(s.channel),
+		"subject": payload.Subject,
+		"body":    payload.Body,
+	}).Info("[MOCK] Ops alert sent successfully")
+
+	return nil
+}
+
+func (s *MockChatSender) GetType() string {
+	return s.channel
+}
+
+func (s *MockChatSender) Name() string {
+	return "mock_" + s.channel
+}
@@ -9,26 +9,37 @@ import (
 
 // MockEmailSender simulates email sending for demo
 type MockEmailSender struct {
-	log *logrus.Logger
+	from string
+	log  *logrus.Logger
 }
 
-func NewMockEmailSender(log *logrus.Logger) *MockEmailSender {
-	return &MockEmailSender{log: log}
+func NewMockEmailSender(from string, log *logrus.Logger) *MockEmailSender {
+	return &MockEmailSender{from: from, log: log}
 }
 
 func (s *MockEmailSender) Send(ctx context.Context, payload NotificationPayload) error {
+	// Build the real MIME message so the demo exercises the same encoding path
+	message, err := BuildEmailMessage(s.from, payload.To, payload.Subject, payload.Body, payload.HTMLBody)
+	if err != nil {
+		return err
+	}
+
 	// Simulate network delay
-	time.Sleep(100 * time.Millisecond)
+	if err := simulateDelay(ctx, 100*time.Millisecond); err != nil {
+		return err
+	}
 
 	// Log as if email was sent
 	s.log.WithFields(logrus.Fields{
-		"type":    "EMAIL",
-		"to":      payload.To,
-		"subject": payload.Subject,
+		"type":      "EMAIL",
+		"to":        payload.To,
+		"subject":   payload.Subject,
+		"html":      payload.HTMLBody != "",
+		"mime_size": len(message),
 	}).Info("[MOCK] Email sent successfully")
 
 	// In real implementation:
-	// return smtp.SendEmail(payload.To, payload.Subject, payload.Body)
+	// return smtp.SendMail(addr, auth, s.from, []string{payload.To}, message)
 
 	return nil
 }
@@ -36,3 +47,7 @@ func (s *MockEmailSender) Send(ctx context.Context, payload NotificationPayload)
 func (s *MockEmailSender) GetType() string {
 	return "email"
 }
+
+func (s *MockEmailSender) Name() string {
+	return "mock_email"
+}
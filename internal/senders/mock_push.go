@@ -18,14 +18,17 @@ func NewMockPushSender(log *logrus.Logger) *MockPushSender {
 
 func (s *MockPushSender) Send(ctx context.Context, payload NotificationPayload) error {
 	// Simulate push notification delay
-	time.Sleep(50 * time.Millisecond)
+	if err := simulateDelay(ctx, 50*time.Millisecond); err != nil {
+		return err
+	}
 
 	// Log as if push was sent
 	s.log.WithFields(logrus.Fields{
-		"type":  "PUSH",
-		"to":    payload.To,
-		"title": payload.Subject,
-		"body":  payload.Body,
+		"type":         "PUSH",
+		"to":           payload.To,
+		"title":        payload.Subject,
+		"body":         payload.Body,
+		"collapse_key": payload.CollapseKey,
 	}).Info("[MOCK] Push notification sent successfully")
 
 	// In real implementation:
@@ -37,3 +40,7 @@ func (s *MockPushSender) Send(ctx context.Context, payload NotificationPayload)
 func (s *MockPushSender) GetType() string {
 	return "push"
 }
+
+func (s *MockPushSender) Name() string {
+	return "mock_push"
+}
@@ -0,0 +1,51 @@
+package senders
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MockSMSSender simulates SMS sending for demo
+type MockSMSSender struct {
+	maxSegments int
+	log         *logrus.Logger
+}
+
+func NewMockSMSSender(maxSegments int, log *logrus.Logger) *MockSMSSender {
+	return &MockSMSSender{maxSegments: maxSegments, log: log}
+}
+
+func (s *MockSMSSender) Send(ctx context.Context, payload NotificationPayload) error {
+	to, err := NormalizePhoneE164(payload.To)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	message := FitSMS(payload.Body, s.maxSegments)
+	info := SMSSegments(message)
+
+	// Simulate gateway delay
+	if err := simulateDelay(ctx, 50*time.Millisecond); err != nil {
+		return err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"type":     "SMS",
+		"to":       to,
+		"message":  message,
+		"encoding": info.Encoding,
+		"segments": info.Segments,
+	}).Info("[MOCK] SMS sent successfully")
+
+	return nil
+}
+
+func (s *MockSMSSender) GetType() string {
+	return "sms"
+}
+
+func (s *MockSMSSender) Name() string {
+	return "mock_sms"
+}
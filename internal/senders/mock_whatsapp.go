@@ -0,0 +1,64 @@
+package senders
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MockWhatsAppSender simulates WhatsApp sending for demo, applying the same
+// session window rules as WhatsAppSender
+type MockWhatsAppSender struct {
+	sessions WhatsAppSessions
+	log      *logrus.Logger
+}
+
+func NewMockWhatsAppSender(sessions WhatsAppSessions, log *logrus.Logger) *MockWhatsAppSender {
+	return &MockWhatsAppSender{sessions: sessions, log: log}
+}
+
+func (s *MockWhatsAppSender) Send(ctx context.Context, payload NotificationPayload) error {
+	to, err := NormalizePhoneE164(payload.To)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	lastInbound, err := s.sessions.LastInbound(ctx, to)
+	if err != nil {
+		return err
+	}
+
+	message, err := buildWhatsAppMessage(to, payload, time.Since(lastInbound) < WhatsAppSessionWindow)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	// Simulate API delay
+	if err := simulateDelay(ctx, 50*time.Millisecond); err != nil {
+		return err
+	}
+
+	fields := logrus.Fields{
+		"type":    "WHATSAPP",
+		"to":      to,
+		"message": message.Type,
+	}
+	if message.Template != nil {
+		fields["template"] = message.Template.Name
+		fields["parameters"] = payload.Template.Parameters
+	} else {
+		fields["body"] = payload.Body
+	}
+	s.log.WithFields(fields).Info("[MOCK] WhatsApp message sent successfully")
+
+	return nil
+}
+
+func (s *MockWhatsAppSender) GetType() string {
+	return "whatsapp"
+}
+
+func (s *MockWhatsAppSender) Name() string {
+	return "mock_whatsapp"
+}
@@ -0,0 +1,111 @@
+package senders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// deviceParallelism bounds the concurrent requests a push sender makes for
+// the devices of one recipient
+const deviceParallelism = 8
+
+// ForEach calls fn for every index below n, running at most limit calls at
+// once, and returns their errors by index after all of them returned. Once
+// ctx is done no further call is started; the indexes left out get ctx's
+// error.
+func ForEach(ctx context.Context, n, limit int, fn func(i int) error) []error {
+	errs := make([]error, n)
+	slots := make(chan struct{}, max(limit, 1))
+	var wg sync.WaitGroup
+
+	for i := range n {
+		if !acquire(ctx, slots) {
+			for j := i; j < n; j++ {
+				errs[j] = ctx.Err()
+			}
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			errs[i] = fn(i)
+		}()
+	}
+
+	wg.Wait()
+	return errs
+}
+
+// acquire takes a slot, or reports false once ctx is done
+func acquire(ctx context.Context, slots chan struct{}) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// devicesResult combines the outcomes of a push to each of a recipient's
+// devices: nil when every live device accepted it, a PartialError when some
+// did, and the joined errors when none did. Devices found to be expired
+// neither accept nor fail; if every device expired there was no target.
+// A send no device accepted is only permanent if every device rejected it,
+// so that a provider outage still counts against the provider.
+func devicesResult(accepted []bool, errs []error) error {
+	delivered, failed, rejected := 0, 0, 0
+	for i := range accepted {
+		switch {
+		case accepted[i]:
+			delivered++
+		case errs[i] != nil:
+			failed++
+			if IsPermanent(errs[i]) {
+				rejected++
+			}
+		}
+	}
+
+	switch {
+	case failed == 0 && delivered == 0:
+		return ErrNoTarget
+	case failed == 0:
+		return nil
+	case delivered == 0 && rejected > 0 && rejected < failed:
+		var transient, permanent []error
+		for _, err := range errs {
+			switch {
+			case err == nil:
+			case IsPermanent(err):
+				permanent = append(permanent, err)
+			default:
+				transient = append(transient, err)
+			}
+		}
+		return fmt.Errorf("%w\nrejected: %v", errors.Join(transient...), errors.Join(permanent...))
+	case delivered == 0:
+		return errors.Join(errs...)
+	default:
+		return &PartialError{Delivered: delivered, Err: errors.Join(errs...)}
+	}
+}
+
+// countErrors counts the non-nil errors
+func countErrors(errs []error) int {
+	count := 0
+	for _, err := range errs {
+		if err != nil {
+			count++
+		}
+	}
+	return count
+}
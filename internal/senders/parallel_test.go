@@ -0,0 +1,61 @@
+package senders
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestForEachLimitsConcurrency(t *testing.T) {
+	const limit = 3
+
+	var running, peak atomic.Int32
+	errs := ForEach(context.Background(), 10, limit, func(i int) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if i%2 == 1 {
+			return errors.New("odd")
+		}
+		return nil
+	})
+
+	if peak.Load() > limit {
+		t.Errorf("%d calls ran at once, limit %d", peak.Load(), limit)
+	}
+	for i, err := range errs {
+		if (err != nil) != (i%2 == 1) {
+			t.Errorf("errs[%d] = %v", i, err)
+		}
+	}
+}
+
+func TestForEachStopsStartingCallsWhenDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var calls atomic.Int32
+	errs := ForEach(ctx, 5, 1, func(i int) error {
+		calls.Add(1)
+		if i == 1 {
+			cancel()
+		}
+		return nil
+	})
+
+	if calls.Load() != 2 {
+		t.Errorf("fn called %d times, want 2", calls.Load())
+	}
+	for i, err := range errs {
+		if want := i >= 2; errors.Is(err, context.Canceled) != want {
+			t.Errorf("errs[%d] = %v, want cancelled %v", i, err, want)
+		}
+	}
+}
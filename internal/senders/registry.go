@@ -0,0 +1,44 @@
+package senders
+
+import "sort"
+
+// Registry maps channel names to the senders delivering on them, keyed by
+// each sender's GetType. A channel may have several senders, e.g. push goes
+// to app devices, browsers and iOS devices.
+type Registry struct {
+	senders map[string][]Sender
+}
+
+// NewRegistry registers the given senders; nil senders (channels that are
+// not configured) are skipped
+func NewRegistry(senders ...Sender) *Registry {
+	r := &Registry{senders: make(map[string][]Sender)}
+	for _, sender := range senders {
+		r.Register(sender)
+	}
+	return r
+}
+
+// Register adds a sender to the channel named by its GetType
+func (r *Registry) Register(sender Sender) {
+	if sender == nil {
+		return
+	}
+	channel := sender.GetType()
+	r.senders[channel] = append(r.senders[channel], sender)
+}
+
+// Get returns the senders registered for channel, in registration order
+func (r *Registry) Get(channel string) []Sender {
+	return r.senders[channel]
+}
+
+// Channels returns the names of every channel with a sender
+func (r *Registry) Channels() []string {
+	channels := make([]string, 0, len(r.senders))
+	for channel := range r.senders {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	return channels
+}
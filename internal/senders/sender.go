@@ -1,13 +1,43 @@
 package senders
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/entities"
+	"github.com/google/uuid"
+)
 
 // NotificationPayload represents data to send
 type NotificationPayload struct {
-	To      string
-	Subject string
-	Body    string
-	Data    map[string]interface{}
+	NotificationID string
+	Type           string
+	Category       string
+
+	To       string
+	Subject  string
+	Body     string
+	HTMLBody string
+	Priority string
+	Data     map[string]interface{}
+
+	// CollapseKey lets push providers replace an earlier notification with
+	// the same key in the device tray
+	CollapseKey string
+
+	// Template is a provider-approved template to send instead of Body when
+	// free text is not allowed
+	Template *TemplateMessage
+}
+
+// TemplateMessage references a provider-approved message template
+type TemplateMessage struct {
+	Name       string
+	Language   string
+	Parameters []string
 }
 
 // Sender interface for notification senders
@@ -15,3 +45,110 @@ type Sender interface {
 	Send(ctx context.Context, payload NotificationPayload) error
 	GetType() string
 }
+
+// Named is implemented by senders that can tell which provider they
+// deliver through, for delivery records
+type Named interface {
+	Name() string
+}
+
+// ProviderName returns the sender's provider name, or its channel when the
+// sender does not name one
+func ProviderName(sender Sender) string {
+	if named, ok := sender.(Named); ok {
+		return named.Name()
+	}
+	return sender.GetType()
+}
+
+// PermanentError marks a failure caused by the notification itself (a bad
+// phone number, a rejected request) that another provider would not fix
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// Permanent marks err as permanent
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent reports whether err is marked permanent. Unmarked errors are
+// treated as transient provider failures.
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// ErrNoTarget is returned when the recipient has nothing to deliver to, such
+// as no registered devices or webhook endpoints. The send is skipped rather
+// than failed.
+var ErrNoTarget = errors.New("recipient has no delivery target")
+
+// PartialError reports a send that reached some of the recipient's devices
+// or endpoints but not all of them. The notification counts as delivered.
+type PartialError struct {
+	Delivered int
+	Err       error
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("delivered to %d targets, others failed: %v", e.Delivered, e.Err)
+}
+
+func (e *PartialError) Unwrap() error { return e.Err }
+
+// IsPartial reports whether err is a partial delivery
+func IsPartial(err error) bool {
+	var partial *PartialError
+	return errors.As(err, &partial)
+}
+
+// providerFailed reports whether err means the provider failed, rather than
+// the notification being rejected, delivered in part or having no target
+func providerFailed(err error) bool {
+	return err != nil && !IsPermanent(err) && !IsPartial(err) && !errors.Is(err, ErrNoTarget)
+}
+
+// rejectedStatus reports whether an HTTP status means the provider rejected
+// the request itself, rather than being unavailable or throttling
+func rejectedStatus(code int) bool {
+	return code >= 400 && code < 500 && code != 408 && code != 429
+}
+
+// DeviceTokens stores the push targets of users' devices, such as APNs
+// tokens and browser push subscriptions
+type DeviceTokens interface {
+	ListActive(ctx context.Context, userID uuid.UUID, platform string) ([]entities.DeviceToken, error)
+	MarkUsed(ctx context.Context, id uuid.UUID) error
+	Deactivate(ctx context.Context, id uuid.UUID) error
+}
+
+// stripURLError drops the request URL from an HTTP client error, for
+// providers whose URLs carry credentials
+func stripURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// simulateDelay waits like a provider round trip in the mock senders,
+// giving up when ctx is done
+func simulateDelay(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,90 @@
+package senders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SlackSender posts ops alerts to a Slack incoming webhook
+type SlackSender struct {
+	webhookURL string
+	client     *http.Client
+	log        *logrus.Logger
+}
+
+func NewSlackSender(webhookURL string, timeout time.Duration, log *logrus.Logger) (*SlackSender, error) {
+	if _, err := url.ParseRequestURI(webhookURL); err != nil {
+		return nil, fmt.Errorf("invalid slack webhook url: %w", err)
+	}
+
+	return &SlackSender{
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: timeout},
+		log:        log,
+	}, nil
+}
+
+// slackMessage is the body of an incoming webhook request
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+func (s *SlackSender) Send(ctx context.Context, payload NotificationPayload) error {
+	body, err := json.Marshal(slackMessage{Text: slackText(payload)})
+	if err != nil {
+		return fmt.Errorf("failed to marshal slack message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// The webhook URL is the credential
+		return fmt.Errorf("slack request failed: %w", stripURLError(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("slack returned %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"type":    "SLACK",
+		"subject": payload.Subject,
+	}).Info("Slack alert sent")
+
+	return nil
+}
+
+// slackText formats the alert as mrkdwn with a bold subject line
+func slackText(payload NotificationPayload) string {
+	escape := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace
+
+	text := escape(payload.Body)
+	if payload.Subject != "" {
+		text = "*" + escape(payload.Subject) + "*\n" + text
+	}
+	return text
+}
+
+func (s *SlackSender) GetType() string {
+	return "slack"
+}
+
+func (s *SlackSender) Name() string {
+	return "slack"
+}
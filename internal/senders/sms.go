@@ -0,0 +1,131 @@
+package senders
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// SMS encodings
+const (
+	SMSEncodingGSM7 = "GSM-7"
+	SMSEncodingUCS2 = "UCS-2"
+)
+
+// gsm7Basic is the GSM 03.38 default alphabet; gsm7Extended characters need
+// an escape and take two septets
+const (
+	gsm7Basic = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+		"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+	gsm7Extended = "^{}\\[~]|€\f"
+)
+
+// SMSInfo describes how a text is split into SMS segments
+type SMSInfo struct {
+	Encoding string
+	// Units is the length in septets (GSM-7) or UTF-16 code units (UCS-2)
+	Units    int
+	Segments int
+}
+
+// SMSSegments reports the encoding and number of segments text needs. A
+// single GSM-7 SMS holds 160 septets and 153 per segment when concatenated;
+// any character outside GSM-7 switches the whole text to UCS-2 (70 / 67).
+func SMSSegments(text string) SMSInfo {
+	if text == "" {
+		return SMSInfo{Encoding: SMSEncodingGSM7}
+	}
+
+	septets, ok := gsm7Length(text)
+	if ok {
+		return SMSInfo{Encoding: SMSEncodingGSM7, Units: septets, Segments: segments(septets, 160, 153)}
+	}
+
+	units := len(utf16.Encode([]rune(text)))
+	return SMSInfo{Encoding: SMSEncodingUCS2, Units: units, Segments: segments(units, 70, 67)}
+}
+
+func gsm7Length(text string) (int, bool) {
+	length := 0
+	for _, r := range text {
+		switch {
+		case strings.ContainsRune(gsm7Basic, r):
+			length++
+		case strings.ContainsRune(gsm7Extended, r):
+			length += 2
+		default:
+			return 0, false
+		}
+	}
+	return length, true
+}
+
+func segments(units, single, multi int) int {
+	if units <= single {
+		return 1
+	}
+	return (units + multi - 1) / multi
+}
+
+// FitSMS truncates text so it fits in maxSegments, marking the cut with
+// "..." (which keeps GSM-7 texts in GSM-7). A non-positive maxSegments means
+// unlimited.
+func FitSMS(text string, maxSegments int) string {
+	if maxSegments <= 0 || SMSSegments(text).Segments <= maxSegments {
+		return text
+	}
+
+	runes := []rune(text)
+	for n := len(runes) - 1; n > 0; n-- {
+		candidate := strings.TrimRightFunc(string(runes[:n]), unicode.IsSpace) + "..."
+		if SMSSegments(candidate).Segments <= maxSegments {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// ErrInvalidPhoneNumber is returned for numbers that can't be normalized
+var ErrInvalidPhoneNumber = errors.New("invalid phone number")
+
+// NormalizePhoneE164 converts a phone number to E.164. Indonesian numbers
+// may be written in national form (0812...), without the plus (62812...) or
+// without the trunk prefix (812...); numbers with another country code must
+// start with "+".
+func NormalizePhoneE164(raw string) (string, error) {
+	var digits strings.Builder
+	plus := false
+	for i, r := range strings.TrimSpace(raw) {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0:
+			plus = true
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", fmt.Errorf("%w: %q", ErrInvalidPhoneNumber, raw)
+		}
+	}
+
+	number := digits.String()
+	switch {
+	case plus && !strings.HasPrefix(number, "62"):
+		// Other countries: only check the E.164 length
+		if len(number) < 8 || len(number) > 15 {
+			return "", fmt.Errorf("%w: %q", ErrInvalidPhoneNumber, raw)
+		}
+		return "+" + number, nil
+	case strings.HasPrefix(number, "62"):
+		number = number[2:]
+	case strings.HasPrefix(number, "0"):
+		number = number[1:]
+	}
+
+	// Indonesian mobile numbers: 8xx followed by 6-9 digits
+	if !strings.HasPrefix(number, "8") || len(number) < 9 || len(number) > 12 {
+		return "", fmt.Errorf("%w: %q", ErrInvalidPhoneNumber, raw)
+	}
+	return "+62" + number, nil
+}
@@ -0,0 +1,145 @@
+package senders
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/configs"
+	"github.com/sirupsen/logrus"
+)
+
+// SMTPSender sends email through an SMTP relay
+type SMTPSender struct {
+	cfg          configs.SMTPConfig
+	from         string
+	envelopeFrom string
+	log          *logrus.Logger
+}
+
+func NewSMTPSender(cfg configs.SMTPConfig, from string, log *logrus.Logger) (*SMTPSender, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("smtp host is required")
+	}
+	address, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email from address %q: %w", from, err)
+	}
+
+	return &SMTPSender{
+		cfg:          cfg,
+		from:         from,
+		envelopeFrom: address.Address,
+		log:          log,
+	}, nil
+}
+
+func (s *SMTPSender) Send(ctx context.Context, payload NotificationPayload) error {
+	to, err := mail.ParseAddress(payload.To)
+	if err != nil {
+		return Permanent(fmt.Errorf("invalid email recipient %q: %w", payload.To, err))
+	}
+
+	message, err := BuildEmailMessage(s.from, payload.To, payload.Subject, payload.Body, payload.HTMLBody)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	if s.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.Timeout)
+		defer cancel()
+	}
+
+	if err := s.deliver(ctx, to.Address, message); err != nil {
+		return err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"type":     "EMAIL",
+		"provider": s.cfg.Name,
+		"to":       payload.To,
+		"subject":  payload.Subject,
+	}).Info("Email sent")
+	return nil
+}
+
+// deliver runs one SMTP session for a single recipient. The connection is
+// closed when ctx is done, which aborts whichever command is in flight.
+func (s *SMTPSender) deliver(ctx context.Context, to string, message []byte) error {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", net.JoinHostPort(s.cfg.Host, s.cfg.Port))
+	if err != nil {
+		return fmt.Errorf("smtp connect failed: %w", err)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake failed: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.cfg.Host}); err != nil {
+			return fmt.Errorf("smtp starttls failed: %w", err)
+		}
+	}
+	if s.cfg.Username != "" {
+		auth := smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("smtp auth failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(s.envelopeFrom); err != nil {
+		return fmt.Errorf("smtp MAIL FROM failed: %w", err)
+	}
+	if err := client.Rcpt(to); err != nil {
+		// A 5xx reply to RCPT rejects the address itself, which another
+		// relay would reject too
+		err = fmt.Errorf("smtp RCPT TO failed: %w", err)
+		if isPermanentReply(err) {
+			return Permanent(err)
+		}
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA failed: %w", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return fmt.Errorf("smtp write failed: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp message rejected: %w", err)
+	}
+
+	// The message is accepted once DATA completes; a failed QUIT must not
+	// make it look undelivered and get it sent twice
+	if err := client.Quit(); err != nil {
+		s.log.WithError(err).WithField("provider", s.cfg.Name).Debug("SMTP QUIT failed")
+	}
+	return nil
+}
+
+// isPermanentReply reports whether err carries an SMTP 5xx reply
+func isPermanentReply(err error) bool {
+	var reply *textproto.Error
+	return errors.As(err, &reply) && reply.Code >= 500
+}
+
+func (s *SMTPSender) GetType() string {
+	return "email"
+}
+
+func (s *SMTPSender) Name() string {
+	return s.cfg.Name
+}
@@ -0,0 +1,108 @@
+package senders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/configs"
+	"github.com/sirupsen/logrus"
+)
+
+// TelegramSender posts ops alerts to a Telegram chat through the Bot API
+type TelegramSender struct {
+	cfg    configs.OpsConfig
+	client *http.Client
+	log    *logrus.Logger
+}
+
+func NewTelegramSender(cfg configs.OpsConfig, log *logrus.Logger) (*TelegramSender, error) {
+	if cfg.TelegramBotToken == "" || cfg.TelegramChatID == "" {
+		return nil, fmt.Errorf("telegram bot token and chat id are required")
+	}
+
+	return &TelegramSender{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		log:    log,
+	}, nil
+}
+
+// telegramMessage is the body of a sendMessage request
+type telegramMessage struct {
+	ChatID                string `json:"chat_id"`
+	Text                  string `json:"text"`
+	ParseMode             string `json:"parse_mode"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+	DisableNotification   bool   `json:"disable_notification"`
+}
+
+// telegramResponse is the envelope of every Bot API response
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+}
+
+func (s *TelegramSender) Send(ctx context.Context, payload NotificationPayload) error {
+	body, err := json.Marshal(telegramMessage{
+		ChatID:                s.cfg.TelegramChatID,
+		Text:                  telegramText(payload),
+		ParseMode:             "HTML",
+		DisableWebPagePreview: true,
+		DisableNotification:   payload.Priority == "low",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal telegram message: %w", err)
+	}
+
+	endpoint := strings.TrimRight(s.cfg.TelegramBaseURL, "/") + "/bot" + s.cfg.TelegramBotToken + "/sendMessage"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build telegram request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// The request URL contains the bot token
+		return fmt.Errorf("telegram request failed: %w", stripURLError(err))
+	}
+	defer resp.Body.Close()
+
+	var result telegramResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("telegram returned %d with unreadable body: %w", resp.StatusCode, err)
+	}
+	if !result.OK {
+		return fmt.Errorf("telegram returned %d: %s", resp.StatusCode, result.Description)
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"type":    "TELEGRAM",
+		"chat_id": s.cfg.TelegramChatID,
+		"subject": payload.Subject,
+	}).Info("Telegram alert sent")
+
+	return nil
+}
+
+// telegramText formats the alert as Telegram HTML with a bold subject line
+func telegramText(payload NotificationPayload) string {
+	text := html.EscapeString(payload.Body)
+	if payload.Subject != "" {
+		text = "<b>" + html.EscapeString(payload.Subject) + "</b>\n" + text
+	}
+	return text
+}
+
+func (s *TelegramSender) GetType() string {
+	return "telegram"
+}
+
+func (s *TelegramSender) Name() string {
+	return "telegram"
+}
@@ -0,0 +1,281 @@
+package senders
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/configs"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/entities"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Webhook request headers
+const (
+	WebhookSignatureHeader = "X-TokoHobby-Signature"
+	WebhookTimestampHeader = "X-TokoHobby-Timestamp"
+	WebhookDeliveryHeader  = "X-TokoHobby-Delivery"
+)
+
+// webhookRecordTimeout bounds recording an endpoint's health, which runs
+// even when the send's own deadline has passed
+const webhookRecordTimeout = 5 * time.Second
+
+// webhookDataFields are the notification data fields sent to webhook
+// endpoints. Endpoints belong to partners and sellers, so anything else in
+// the event metadata (the buyer's phone number, internal IDs) stays out.
+var webhookDataFields = []string{
+	"order_id",
+	"status",
+	"item_count",
+	"total_amount",
+	"paid_amount",
+	"payment_method",
+	"cancelled_by",
+	"cancel_reason",
+	"cancellation_fee",
+	"refund_amount",
+	"tracking_number",
+	"courier",
+	"estimated_arrival",
+}
+
+// WebhookSubscriptions stores webhook endpoints and their delivery health
+type WebhookSubscriptions interface {
+	ListActive(ctx context.Context, userID uuid.UUID, notifType string) ([]entities.WebhookSubscription, error)
+	RecordSuccess(ctx context.Context, id uuid.UUID) error
+	RecordFailure(ctx context.Context, id uuid.UUID, lastErr string, disableAfter int) (bool, error)
+}
+
+// WebhookSender POSTs notifications as JSON to every active endpoint of the
+// recipient, signed with the endpoint's secret
+type WebhookSender struct {
+	cfg    configs.WebhookConfig
+	subs   WebhookSubscriptions
+	client *http.Client
+	log    *logrus.Logger
+}
+
+func NewWebhookSender(cfg configs.WebhookConfig, subs WebhookSubscriptions, log *logrus.Logger) *WebhookSender {
+	cfg.MaxAttempts = max(cfg.MaxAttempts, 1)
+	return &WebhookSender{
+		cfg:    cfg,
+		subs:   subs,
+		client: &http.Client{Timeout: cfg.Timeout},
+		log:    log,
+	}
+}
+
+// WebhookEvent is the JSON body delivered to webhook endpoints
+type WebhookEvent struct {
+	ID       string                 `json:"id"`
+	Event    string                 `json:"event"`
+	UserID   string                 `json:"user_id"`
+	Type     string                 `json:"type"`
+	Category string                 `json:"category"`
+	Title    string                 `json:"title"`
+	Message  string                 `json:"message"`
+	Priority string                 `json:"priority,omitempty"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+	SentAt   time.Time              `json:"sent_at"`
+}
+
+// SignWebhook returns the signature header value for a delivery: the hex
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the endpoint secret.
+// Receivers should recompute it and reject stale timestamps.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *WebhookSender) Send(ctx context.Context, payload NotificationPayload) error {
+	userID, err := uuid.Parse(payload.To)
+	if err != nil {
+		return fmt.Errorf("invalid webhook recipient %q: %w", payload.To, err)
+	}
+
+	subs, err := s.subs.ListActive(ctx, userID, payload.Type)
+	if err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		return ErrNoTarget
+	}
+
+	body, err := json.Marshal(WebhookEvent{
+		ID:       payload.NotificationID,
+		Event:    "notification.created",
+		UserID:   payload.To,
+		Type:     payload.Type,
+		Category: payload.Category,
+		Title:    payload.Subject,
+		Message:  payload.Body,
+		Priority: payload.Priority,
+		Data:     webhookData(payload.Data),
+		SentAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook event: %w", err)
+	}
+
+	var errs []error
+	delivered := 0
+	for _, sub := range subs {
+		log := s.log.WithFields(logrus.Fields{
+			"type":            "WEBHOOK",
+			"subscription_id": sub.ID,
+			"url":             sub.URL,
+		})
+
+		err := s.deliver(ctx, sub, payload.NotificationID, body)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", sub.URL, err))
+		} else {
+			log.Info("Webhook delivered")
+			delivered++
+		}
+		s.recordHealth(ctx, log, sub, err)
+	}
+
+	if delivered > 0 && len(errs) > 0 {
+		return &PartialError{Delivered: delivered, Err: errors.Join(errs...)}
+	}
+	return errors.Join(errs...)
+}
+
+// recordHealth stores the outcome of a delivery, disabling the endpoint
+// after DisableAfter failures in a row. It runs after ctx is done too, since
+// a delivery cut short by the deadline still failed.
+func (s *WebhookSender) recordHealth(ctx context.Context, log *logrus.Entry, sub entities.WebhookSubscription, deliverErr error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), webhookRecordTimeout)
+	defer cancel()
+
+	if deliverErr == nil {
+		if err := s.subs.RecordSuccess(ctx, sub.ID); err != nil {
+			log.WithError(err).Warn("Failed to record webhook success")
+		}
+		return
+	}
+
+	disabled, err := s.subs.RecordFailure(ctx, sub.ID, deliverErr.Error(), s.cfg.DisableAfter)
+	if err != nil {
+		log.WithError(err).Warn("Failed to record webhook failure")
+	}
+	if disabled {
+		log.WithError(deliverErr).Warn("Webhook disabled after repeated failures")
+	}
+}
+
+// deliver POSTs body to the endpoint, retrying network errors, 429 and 5xx
+// responses with exponential backoff. No retry is started that could not
+// finish before ctx's deadline.
+func (s *WebhookSender) deliver(ctx context.Context, sub entities.WebhookSubscription, deliveryID string, body []byte) error {
+	var lastErr error
+
+	for attempt := 1; attempt <= s.cfg.MaxAttempts; attempt++ {
+		retryAfter, err := s.post(ctx, sub, deliveryID, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		if IsPermanent(err) || attempt == s.cfg.MaxAttempts {
+			break
+		}
+
+		wait := s.backoff(attempt)
+		if retryAfter > wait && retryAfter <= s.cfg.MaxBackoff {
+			wait = retryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait+s.cfg.Timeout {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(lastErr, ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+
+	return lastErr
+}
+
+func (s *WebhookSender) post(ctx context.Context, sub entities.WebhookSubscription, deliveryID string, body []byte) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, Permanent(fmt.Errorf("failed to build webhook request: %w", err))
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TokoHobby-Webhooks/1.0")
+	req.Header.Set(WebhookDeliveryHeader, deliveryID)
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(sub.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return 0, nil
+	}
+
+	err = fmt.Errorf("endpoint returned %d", resp.StatusCode)
+	if detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512)); len(bytes.TrimSpace(detail)) > 0 {
+		err = fmt.Errorf("%w: %s", err, strings.TrimSpace(string(detail)))
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		seconds, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return time.Duration(seconds) * time.Second, err
+	}
+	return 0, Permanent(err)
+}
+
+// webhookData copies the allow-listed fields out of the notification data
+func webhookData(data map[string]interface{}) map[string]interface{} {
+	allowed := make(map[string]interface{})
+	for _, field := range webhookDataFields {
+		if value, ok := data[field]; ok {
+			allowed[field] = value
+		}
+	}
+	return allowed
+}
+
+// backoff doubles the delay per attempt, capped at MaxBackoff, with up to
+// 20% jitter so retries from many workers don't line up
+func (s *WebhookSender) backoff(attempt int) time.Duration {
+	delay := s.cfg.InitialBackoff << (attempt - 1)
+	if delay <= 0 || delay > s.cfg.MaxBackoff {
+		delay = s.cfg.MaxBackoff
+	}
+	return delay + time.Duration(rand.Int64N(int64(delay)/5+1))
+}
+
+func (s *WebhookSender) GetType() string {
+	return "webhook"
+}
+
+func (s *WebhookSender) Name() string {
+	return "webhook"
+}
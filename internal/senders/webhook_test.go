@@ -0,0 +1,209 @@
+package senders
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/configs"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/entities"
+	"github.com/google/uuid"
+)
+
+func TestSignWebhook(t *testing.T) {
+	got := SignWebhook("whsec_test", 1700000000, []byte(`{"id":"1"}`))
+	want := "sha256=11bf4466ea17c3df3fd743af0b435368e16b7a05eb8eced85e8c4670767bdec5"
+	if got != want {
+		t.Errorf("SignWebhook() = %q, want %q", got, want)
+	}
+}
+
+// fakeWebhookSubs is an in-memory WebhookSubscriptions that remembers
+// whether health was recorded with a live context
+type fakeWebhookSubs struct {
+	mu        sync.Mutex
+	subs      []entities.WebhookSubscription
+	successes int
+	failures  int
+	deadCtx   bool
+}
+
+func (f *fakeWebhookSubs) ListActive(ctx context.Context, userID uuid.UUID, notifType string) ([]entities.WebhookSubscription, error) {
+	return f.subs, nil
+}
+
+func (f *fakeWebhookSubs) RecordSuccess(ctx context.Context, id uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.successes++
+	f.deadCtx = f.deadCtx || ctx.Err() != nil
+	return ctx.Err()
+}
+
+func (f *fakeWebhookSubs) RecordFailure(ctx context.Context, id uuid.UUID, lastErr string, disableAfter int) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures++
+	f.deadCtx = f.deadCtx || ctx.Err() != nil
+	return false, ctx.Err()
+}
+
+// webhookEndpoint answers with statuses in turn, then 204, and checks the
+// signature of every request
+type webhookEndpoint struct {
+	t        *testing.T
+	secret   string
+	mu       sync.Mutex
+	statuses []int
+	calls    int
+	events   []WebhookEvent
+}
+
+func (e *webhookEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	timestamp, _ := strconv.ParseInt(r.Header.Get(WebhookTimestampHeader), 10, 64)
+	if got, want := r.Header.Get(WebhookSignatureHeader), SignWebhook(e.secret, timestamp, body); got != want {
+		e.t.Errorf("signature = %q, want %q", got, want)
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		e.t.Errorf("invalid webhook body: %v", err)
+	}
+
+	e.mu.Lock()
+	e.calls++
+	e.events = append(e.events, event)
+	status := http.StatusNoContent
+	if len(e.statuses) > 0 {
+		status, e.statuses = e.statuses[0], e.statuses[1:]
+	}
+	e.mu.Unlock()
+
+	w.WriteHeader(status)
+}
+
+func newTestWebhook(t *testing.T, statuses ...int) (*WebhookSender, *webhookEndpoint, *fakeWebhookSubs) {
+	t.Helper()
+
+	endpoint := &webhookEndpoint{t: t, secret: "whsec_test", statuses: statuses}
+	server := httptest.NewServer(endpoint)
+	t.Cleanup(server.Close)
+
+	subs := &fakeWebhookSubs{subs: []entities.WebhookSubscription{{
+		ID:     uuid.New(),
+		URL:    server.URL + "/hooks/tokohobby",
+		Secret: endpoint.secret,
+	}}}
+
+	sender := NewWebhookSender(configs.WebhookConfig{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+		DisableAfter:   5,
+		Timeout:        time.Second,
+	}, subs, quietLogger())
+	return sender, endpoint, subs
+}
+
+func TestWebhookSenderSend(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantCalls    int
+		wantErr      bool
+		wantRejected bool
+	}{
+		{
+			name:      "delivered",
+			wantCalls: 1,
+		},
+		{
+			name:      "server errors are retried",
+			statuses:  []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			wantCalls: 3,
+		},
+		{
+			name:      "retries run out",
+			statuses:  []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			wantCalls: 3,
+			wantErr:   true,
+		},
+		{
+			name:         "client errors are not retried",
+			statuses:     []int{http.StatusUnauthorized},
+			wantCalls:    1,
+			wantErr:      true,
+			wantRejected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender, endpoint, subs := newTestWebhook(t, tt.statuses...)
+
+			err := sender.Send(context.Background(), NotificationPayload{
+				NotificationID: uuid.NewString(),
+				To:             uuid.NewString(),
+				Type:           "order",
+				Data:           map[string]interface{}{"order_id": "ORD-1", "phone_number": "081234567890"},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if IsPermanent(err) != tt.wantRejected {
+				t.Errorf("IsPermanent(%v) = %v, want %v", err, IsPermanent(err), tt.wantRejected)
+			}
+			if endpoint.calls != tt.wantCalls {
+				t.Errorf("endpoint called %d times, want %d", endpoint.calls, tt.wantCalls)
+			}
+			if (tt.wantErr && subs.failures != 1) || (!tt.wantErr && subs.successes != 1) {
+				t.Errorf("recorded %d successes and %d failures", subs.successes, subs.failures)
+			}
+
+			data := endpoint.events[0].Data
+			if data["order_id"] != "ORD-1" || data["phone_number"] != nil {
+				t.Errorf("data = %v, want only the allow-listed fields", data)
+			}
+		})
+	}
+}
+
+func TestWebhookSenderStopsRetryingAtTheDeadline(t *testing.T) {
+	sender, endpoint, subs := newTestWebhook(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+
+	// Too little time is left for a retry and its request timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	if err := sender.Send(ctx, NotificationPayload{To: uuid.NewString()}); err == nil {
+		t.Fatal("Send() succeeded, want an error")
+	}
+	if endpoint.calls != 1 {
+		t.Errorf("endpoint called %d times, want 1", endpoint.calls)
+	}
+	if subs.failures != 1 {
+		t.Errorf("recorded %d failures, want 1", subs.failures)
+	}
+}
+
+func TestWebhookSenderRecordsHealthAfterTheDeadline(t *testing.T) {
+	sender, _, subs := newTestWebhook(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	sender.Send(ctx, NotificationPayload{To: uuid.NewString()})
+	if subs.failures != 1 {
+		t.Errorf("recorded %d failures, want 1", subs.failures)
+	}
+	if subs.deadCtx {
+		t.Error("health was recorded with the expired context")
+	}
+}
@@ -0,0 +1,216 @@
+package senders
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/configs"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/entities"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// errWebPushGone means the push service dropped the subscription
+var errWebPushGone = errors.New("web push subscription expired")
+
+// WebPushSender delivers push notifications to every browser the recipient
+// subscribed, using VAPID and RFC 8291 payload encryption
+type WebPushSender struct {
+	cfg    configs.WebPushConfig
+	subs   DeviceTokens
+	vapid  *vapidSigner
+	client *http.Client
+	log    *logrus.Logger
+}
+
+func NewWebPushSender(cfg configs.WebPushConfig, subs DeviceTokens, log *logrus.Logger) (*WebPushSender, error) {
+	vapid, err := newVAPIDSigner(cfg.VAPIDPrivateKey, cfg.VAPIDPublicKey, cfg.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebPushSender{
+		cfg:    cfg,
+		subs:   subs,
+		vapid:  vapid,
+		client: &http.Client{Timeout: cfg.Timeout},
+		log:    log,
+	}, nil
+}
+
+// WebPushMessage is the decrypted payload the storefront's service worker
+// receives
+type WebPushMessage struct {
+	ID       string                 `json:"id,omitempty"`
+	Title    string                 `json:"title"`
+	Body     string                 `json:"body"`
+	Tag      string                 `json:"tag,omitempty"`
+	Priority string                 `json:"priority,omitempty"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+}
+
+func (s *WebPushSender) Send(ctx context.Context, payload NotificationPayload) error {
+	userID, err := uuid.Parse(payload.To)
+	if err != nil {
+		return Permanent(fmt.Errorf("invalid web push recipient %q: %w", payload.To, err))
+	}
+
+	subs, err := s.subs.ListActive(ctx, userID, entities.PlatformWeb)
+	if err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		s.log.WithField("user_id", payload.To).Debug("No web push subscriptions")
+		return ErrNoTarget
+	}
+
+	message, err := webPushMessage(payload)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	// Subscriptions are delivered concurrently
+	accepted := make([]bool, len(subs))
+	errs := ForEach(ctx, len(subs), deviceParallelism, func(i int) error {
+		sub := subs[i]
+		err := s.deliver(ctx, sub, message, payload)
+		switch {
+		case errors.Is(err, errWebPushGone):
+			s.log.WithField("subscription_id", sub.ID).Info("Web push subscription expired, deactivating")
+			if err := s.subs.Deactivate(ctx, sub.ID); err != nil {
+				s.log.WithError(err).Warn("Failed to deactivate web push subscription")
+			}
+			return nil
+		case err != nil:
+			s.log.WithError(err).WithField("subscription_id", sub.ID).Warn("Web push to subscription failed")
+			return fmt.Errorf("subscription %s: %w", sub.ID, err)
+		default:
+			accepted[i] = true
+			if err := s.subs.MarkUsed(ctx, sub.ID); err != nil {
+				s.log.WithError(err).Warn("Failed to record web push delivery")
+			}
+			return nil
+		}
+	})
+
+	err = devicesResult(accepted, errs)
+	s.log.WithFields(logrus.Fields{
+		"type":          "WEBPUSH",
+		"to":            payload.To,
+		"subscriptions": len(subs),
+		"failed":        countErrors(errs),
+	}).Info("Web push sent")
+
+	return err
+}
+
+// webPushMessage encodes the payload, dropping Data if it would not fit
+func webPushMessage(payload NotificationPayload) ([]byte, error) {
+	message := WebPushMessage{
+		ID:       payload.NotificationID,
+		Title:    payload.Subject,
+		Body:     payload.Body,
+		Tag:      payload.CollapseKey,
+		Priority: payload.Priority,
+		Data:     payload.Data,
+	}
+
+	encoded, err := json.Marshal(message)
+	if err == nil && len(encoded) > webPushMaxPlaintext {
+		message.Data = nil
+		encoded, err = json.Marshal(message)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal web push message: %w", err)
+	}
+	return encoded, nil
+}
+
+func (s *WebPushSender) deliver(ctx context.Context, sub entities.DeviceToken, message []byte, payload NotificationPayload) error {
+	endpoint, err := url.Parse(sub.Token)
+	if err != nil || endpoint.Host == "" {
+		return Permanent(fmt.Errorf("invalid web push endpoint"))
+	}
+
+	// Keys that don't encrypt belong to a broken subscription
+	body, err := encryptWebPush(message, sub.P256dh, sub.Auth)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	authorization, err := s.vapid.header(endpoint.Scheme + "://" + endpoint.Host)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Token, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build web push request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(s.cfg.TTL.Seconds())))
+	req.Header.Set("Urgency", webPushUrgency(payload.Priority))
+	req.Header.Set("Authorization", authorization)
+	if payload.CollapseKey != "" {
+		req.Header.Set("Topic", webPushTopic(payload.CollapseKey))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// Endpoints carry the subscription's capability token
+		return fmt.Errorf("web push request failed: %w", stripURLError(err))
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return errWebPushGone
+	default:
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err := fmt.Errorf("push service returned %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+		if rejectedStatus(resp.StatusCode) {
+			return Permanent(err)
+		}
+		return err
+	}
+}
+
+// webPushUrgency maps notification priority to the Urgency header
+func webPushUrgency(priority string) string {
+	switch priority {
+	case "low":
+		return "low"
+	case "high", "critical":
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+// webPushTopic derives a Topic header from a collapse key. Topics are at most
+// 32 characters of the base64url alphabet, so the key is hashed.
+func webPushTopic(collapseKey string) string {
+	sum := sha256.Sum256([]byte(collapseKey))
+	return base64.RawURLEncoding.EncodeToString(sum[:])[:32]
+}
+
+func (s *WebPushSender) GetType() string {
+	return "push"
+}
+
+func (s *WebPushSender) Name() string {
+	return "webpush"
+}
@@ -0,0 +1,202 @@
+package senders
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+// webPushRecordSize is the aes128gcm record size. A push message is a
+// single record and push services accept at most 4096 bytes.
+const webPushRecordSize = 4096
+
+// webPushMaxPlaintext leaves room for the header (salt, record size, key id
+// length, 65 byte key id), the padding delimiter and the GCM tag
+const webPushMaxPlaintext = webPushRecordSize - (16 + 4 + 1 + 65) - 1 - 16
+
+// encryptWebPush encrypts plaintext for a subscription as described in
+// RFC 8291, using the content coding of RFC 8188
+func encryptWebPush(plaintext []byte, p256dh, auth string) ([]byte, error) {
+	asKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate web push key: %w", err)
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate web push salt: %w", err)
+	}
+
+	return encryptWebPushWith(plaintext, p256dh, auth, asKey, salt)
+}
+
+// encryptWebPushWith encrypts with the given application server key and salt
+func encryptWebPushWith(plaintext []byte, p256dh, auth string, asKey *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	if len(plaintext) > webPushMaxPlaintext {
+		return nil, fmt.Errorf("web push payload of %d bytes exceeds %d", len(plaintext), webPushMaxPlaintext)
+	}
+
+	uaPublic, err := decodeBase64URL(p256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	uaKey, err := ecdh.P256().NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	authSecret, err := decodeBase64URL(auth)
+	if err != nil || len(authSecret) != 16 {
+		return nil, fmt.Errorf("invalid auth secret")
+	}
+
+	ecdhSecret, err := asKey.ECDH(uaKey)
+	if err != nil {
+		return nil, fmt.Errorf("web push key agreement failed: %w", err)
+	}
+	asPublic := asKey.PublicKey().Bytes()
+
+	// IKM = HKDF(auth_secret, ecdh_secret, "WebPush: info" || 0x00 || ua_public || as_public)
+	keyInfo := "WebPush: info\x00" + string(uaPublic) + string(asPublic)
+	ikm, err := hkdf.Key(sha256.New, ecdhSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	cek, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// Header: salt || record size || key id length || key id (as_public)
+	body := make([]byte, 0, 16+4+1+len(asPublic)+len(plaintext)+1+gcm.Overhead())
+	body = append(body, salt...)
+	body = binary.BigEndian.AppendUint32(body, webPushRecordSize)
+	body = append(body, byte(len(asPublic)))
+	body = append(body, asPublic...)
+
+	// The last (and only) record ends with the 0x02 padding delimiter
+	record := append(append(make([]byte, 0, len(plaintext)+1), plaintext...), 0x02)
+	return gcm.Seal(body, nonce, record, nil), nil
+}
+
+// vapidSigner builds the VAPID (RFC 8292) Authorization header, caching one
+// token per push service origin
+type vapidSigner struct {
+	key       *ecdsa.PrivateKey
+	publicKey string
+	subject   string
+
+	mu     sync.Mutex
+	tokens map[string]vapidToken
+}
+
+type vapidToken struct {
+	jwt     string
+	expires time.Time
+}
+
+// vapidTokenTTL is below the 24h maximum push services accept
+const vapidTokenTTL = 12 * time.Hour
+
+func newVAPIDSigner(privateKey, publicKey, subject string) (*vapidSigner, error) {
+	d, err := decodeBase64URL(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vapid private key: %w", err)
+	}
+	ecdhKey, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vapid private key: %w", err)
+	}
+
+	public := ecdhKey.PublicKey().Bytes()
+	encodedPublic := base64.RawURLEncoding.EncodeToString(public)
+	if publicKey != "" && strings.TrimRight(publicKey, "=") != encodedPublic {
+		return nil, fmt.Errorf("vapid public key does not match private key")
+	}
+	if subject == "" {
+		return nil, fmt.Errorf("vapid subject is required")
+	}
+
+	key := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(public[1:33]),
+			Y:     new(big.Int).SetBytes(public[33:]),
+		},
+		D: new(big.Int).SetBytes(d),
+	}
+
+	return &vapidSigner{
+		key:       key,
+		publicKey: encodedPublic,
+		subject:   subject,
+		tokens:    make(map[string]vapidToken),
+	}, nil
+}
+
+// header returns the Authorization header value for a push service origin
+func (v *vapidSigner) header(audience string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := time.Now()
+	token, ok := v.tokens[audience]
+	if !ok || now.After(token.expires.Add(-time.Hour)) {
+		expires := now.Add(vapidTokenTTL)
+		jwt, err := v.sign(audience, expires)
+		if err != nil {
+			return "", err
+		}
+		token = vapidToken{jwt: jwt, expires: expires}
+		v.tokens[audience] = token
+	}
+
+	return "vapid t=" + token.jwt + ", k=" + v.publicKey, nil
+}
+
+// sign creates an ES256 JWT for the audience
+func (v *vapidSigner) sign(audience string, expires time.Time) (string, error) {
+	header := map[string]interface{}{"typ": "JWT", "alg": "ES256"}
+	claims := map[string]interface{}{
+		"aud": audience,
+		"exp": expires.Unix(),
+		"sub": v.subject,
+	}
+
+	jwt, err := signES256(v.key, header, claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign vapid token: %w", err)
+	}
+	return jwt, nil
+}
+
+// decodeBase64URL decodes base64url with or without padding, as browsers
+// and key generators differ
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
@@ -0,0 +1,91 @@
+package senders
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"encoding/base64"
+	"testing"
+)
+
+// The example from RFC 8291, Appendix A
+const (
+	rfc8291Plaintext  = "When I grow up, I want to be a watermelon"
+	rfc8291ASPrivate  = "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"
+	rfc8291UAPublic   = "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"
+	rfc8291AuthSecret = "BTBZMqHH6r4Tts7J_aSIgg"
+	rfc8291Salt       = "DGv6ra1nlYgDCS1FRnbzlw"
+	rfc8291Message    = "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+)
+
+func TestEncryptWebPushRFC8291Vector(t *testing.T) {
+	asPrivate := mustDecodeBase64URL(t, rfc8291ASPrivate)
+	asKey, err := ecdh.P256().NewPrivateKey(asPrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := encryptWebPushWith([]byte(rfc8291Plaintext), rfc8291UAPublic, rfc8291AuthSecret, asKey, mustDecodeBase64URL(t, rfc8291Salt))
+	if err != nil {
+		t.Fatalf("encryptWebPushWith() error = %v", err)
+	}
+
+	if want := mustDecodeBase64URL(t, rfc8291Message); !bytes.Equal(got, want) {
+		t.Errorf("encryptWebPushWith() =\n%s\nwant\n%s", base64.RawURLEncoding.EncodeToString(got), rfc8291Message)
+	}
+}
+
+func TestEncryptWebPushRejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name      string
+		plaintext []byte
+		p256dh    string
+		auth      string
+	}{
+		{
+			name:      "payload too large",
+			plaintext: make([]byte, webPushMaxPlaintext+1),
+			p256dh:    rfc8291UAPublic,
+			auth:      rfc8291AuthSecret,
+		},
+		{
+			name:      "key not on the curve",
+			plaintext: []byte(rfc8291Plaintext),
+			p256dh:    base64.RawURLEncoding.EncodeToString(make([]byte, 65)),
+			auth:      rfc8291AuthSecret,
+		},
+		{
+			name:      "short auth secret",
+			plaintext: []byte(rfc8291Plaintext),
+			p256dh:    rfc8291UAPublic,
+			auth:      "BTBZMqHH6r4T",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := encryptWebPush(tt.plaintext, tt.p256dh, tt.auth); err == nil {
+				t.Error("encryptWebPush() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestEncryptWebPushMaxPlaintextFitsOneRecord(t *testing.T) {
+	got, err := encryptWebPush(make([]byte, webPushMaxPlaintext), rfc8291UAPublic, rfc8291AuthSecret)
+	if err != nil {
+		t.Fatalf("encryptWebPush() error = %v", err)
+	}
+	if len(got) != webPushRecordSize {
+		t.Errorf("message is %d bytes, want %d", len(got), webPushRecordSize)
+	}
+}
+
+func mustDecodeBase64URL(t *testing.T, value string) []byte {
+	t.Helper()
+
+	data, err := decodeBase64URL(value)
+	if err != nil {
+		t.Fatalf("invalid base64url %q: %v", value, err)
+	}
+	return data
+}
@@ -0,0 +1,134 @@
+package senders
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/configs"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/entities"
+	"github.com/google/uuid"
+)
+
+func TestWebPushSenderSendResults(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          int
+		endpoint        string
+		wantErr         bool
+		wantRejected    bool
+		wantNoTarget    bool
+		wantDeactivated int
+	}{
+		{
+			name:   "accepted",
+			status: http.StatusCreated,
+		},
+		{
+			name:            "expired subscription is deactivated, not failed",
+			status:          http.StatusGone,
+			wantErr:         true,
+			wantNoTarget:    true,
+			wantDeactivated: 1,
+		},
+		{
+			name:         "rejected request",
+			status:       http.StatusRequestEntityTooLarge,
+			wantErr:      true,
+			wantRejected: true,
+		},
+		{
+			name:    "throttled",
+			status:  http.StatusTooManyRequests,
+			wantErr: true,
+		},
+		{
+			name:    "push service unavailable",
+			status:  http.StatusServiceUnavailable,
+			wantErr: true,
+		},
+		{
+			name:         "malformed endpoint",
+			endpoint:     "not a url",
+			wantErr:      true,
+			wantRejected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			endpoint := tt.endpoint
+			if endpoint == "" {
+				endpoint = server.URL + "/push/subscription"
+			}
+
+			userID := uuid.New()
+			subs := &fakeDevices{devices: []entities.DeviceToken{{
+				ID:       uuid.New(),
+				UserID:   userID,
+				Platform: entities.PlatformWeb,
+				Token:    endpoint,
+				P256dh:   rfc8291UAPublic,
+				Auth:     rfc8291AuthSecret,
+				Active:   true,
+			}}}
+
+			vapidKey, err := ecdh.P256().GenerateKey(rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sender, err := NewWebPushSender(configs.WebPushConfig{
+				VAPIDPrivateKey: base64.RawURLEncoding.EncodeToString(vapidKey.Bytes()),
+				Subject:         "mailto:ops@tokohobby.id",
+				TTL:             time.Hour,
+				Timeout:         5 * time.Second,
+			}, subs, quietLogger())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = sender.Send(context.Background(), NotificationPayload{To: userID.String(), Subject: "Halo", Body: "Pesanan dikirim"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if IsPermanent(err) != tt.wantRejected {
+				t.Errorf("IsPermanent(%v) = %v, want %v", err, IsPermanent(err), tt.wantRejected)
+			}
+			if errors.Is(err, ErrNoTarget) != tt.wantNoTarget {
+				t.Errorf("Send() error = %v, want ErrNoTarget %v", err, tt.wantNoTarget)
+			}
+			if len(subs.deactivated) != tt.wantDeactivated {
+				t.Errorf("deactivated %d subscriptions, want %d", len(subs.deactivated), tt.wantDeactivated)
+			}
+		})
+	}
+}
+
+func TestWebPushSenderRejectsInvalidRecipient(t *testing.T) {
+	vapidKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := NewWebPushSender(configs.WebPushConfig{
+		VAPIDPrivateKey: base64.RawURLEncoding.EncodeToString(vapidKey.Bytes()),
+		Subject:         "mailto:ops@tokohobby.id",
+	}, &fakeDevices{}, quietLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sender.Send(context.Background(), NotificationPayload{To: "not-a-uuid"}); !IsPermanent(err) {
+		t.Errorf("Send() error = %v, want a permanent error", err)
+	}
+}
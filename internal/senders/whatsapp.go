@@ -0,0 +1,191 @@
+package senders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/configs"
+	"github.com/sirupsen/logrus"
+)
+
+// WhatsAppSessionWindow is how long after a user's last message free-form
+// messages may be sent
+const WhatsAppSessionWindow = 24 * time.Hour
+
+// ErrWhatsAppSessionClosed is returned when the session window is closed and
+// the notification has no approved template
+var ErrWhatsAppSessionClosed = errors.New("whatsapp session window closed and no approved template")
+
+// WhatsAppSessions reports when a phone number last messaged us
+type WhatsAppSessions interface {
+	LastInbound(ctx context.Context, phoneNumber string) (time.Time, error)
+}
+
+// WhatsAppSender sends messages through the WhatsApp Cloud API. Within 24
+// hours of the user's last message the body is sent as text; otherwise the
+// payload's approved template is used.
+type WhatsAppSender struct {
+	cfg      configs.WhatsAppConfig
+	sessions WhatsAppSessions
+	client   *http.Client
+	log      *logrus.Logger
+}
+
+func NewWhatsAppSender(cfg configs.WhatsAppConfig, sessions WhatsAppSessions, log *logrus.Logger) (*WhatsAppSender, error) {
+	if cfg.PhoneNumberID == "" || cfg.AccessToken == "" {
+		return nil, fmt.Errorf("whatsapp phone number id and access token are required")
+	}
+
+	return &WhatsAppSender{
+		cfg:      cfg,
+		sessions: sessions,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		log:      log,
+	}, nil
+}
+
+type whatsAppMessage struct {
+	MessagingProduct string            `json:"messaging_product"`
+	To               string            `json:"to"`
+	Type             string            `json:"type"`
+	Text             *whatsAppText     `json:"text,omitempty"`
+	Template         *whatsAppTemplate `json:"template,omitempty"`
+}
+
+type whatsAppText struct {
+	Body string `json:"body"`
+}
+
+type whatsAppTemplate struct {
+	Name       string              `json:"name"`
+	Language   whatsAppLanguage    `json:"language"`
+	Components []whatsAppComponent `json:"components,omitempty"`
+}
+
+type whatsAppLanguage struct {
+	Code string `json:"code"`
+}
+
+type whatsAppComponent struct {
+	Type       string              `json:"type"`
+	Parameters []whatsAppParameter `json:"parameters"`
+}
+
+type whatsAppParameter struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type whatsAppError struct {
+	Error struct {
+		Message string `json:"message"`
+		Code    int    `json:"code"`
+	} `json:"error"`
+}
+
+func (s *WhatsAppSender) Send(ctx context.Context, payload NotificationPayload) error {
+	to, err := NormalizePhoneE164(payload.To)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	lastInbound, err := s.sessions.LastInbound(ctx, to)
+	if err != nil {
+		return err
+	}
+
+	message, err := buildWhatsAppMessage(strings.TrimPrefix(to, "+"), payload, time.Since(lastInbound) < WhatsAppSessionWindow)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal whatsapp message: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/%s/messages", strings.TrimRight(s.cfg.BaseURL, "/"), s.cfg.PhoneNumberID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build whatsapp request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.cfg.AccessToken)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("whatsapp request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		err := fmt.Errorf("whatsapp api returned %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+		var apiErr whatsAppError
+		if json.Unmarshal(detail, &apiErr) == nil && apiErr.Error.Message != "" {
+			err = fmt.Errorf("whatsapp api returned %d: %s (code %d)", resp.StatusCode, apiErr.Error.Message, apiErr.Error.Code)
+		}
+		if rejectedStatus(resp.StatusCode) {
+			return Permanent(err)
+		}
+		return err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"type":    "WHATSAPP",
+		"to":      to,
+		"message": message.Type,
+	}).Info("WhatsApp message sent")
+
+	return nil
+}
+
+// buildWhatsAppMessage sends text inside the session window and the approved
+// template outside it
+func buildWhatsAppMessage(to string, payload NotificationPayload, sessionOpen bool) (*whatsAppMessage, error) {
+	message := &whatsAppMessage{
+		MessagingProduct: "whatsapp",
+		To:               to,
+	}
+
+	if sessionOpen && payload.Body != "" {
+		message.Type = "text"
+		message.Text = &whatsAppText{Body: payload.Body}
+		return message, nil
+	}
+
+	if payload.Template == nil {
+		return nil, ErrWhatsAppSessionClosed
+	}
+
+	template := &whatsAppTemplate{
+		Name:     payload.Template.Name,
+		Language: whatsAppLanguage{Code: payload.Template.Language},
+	}
+	if len(payload.Template.Parameters) > 0 {
+		params := make([]whatsAppParameter, len(payload.Template.Parameters))
+		for i, value := range payload.Template.Parameters {
+			params[i] = whatsAppParameter{Type: "text", Text: value}
+		}
+		template.Components = []whatsAppComponent{{Type: "body", Parameters: params}}
+	}
+
+	message.Type = "template"
+	message.Template = template
+	return message, nil
+}
+
+func (s *WhatsAppSender) GetType() string {
+	return "whatsapp"
+}
+
+func (s *WhatsAppSender) Name() string {
+	return "whatsapp_cloud"
+}
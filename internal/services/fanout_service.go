@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrFanoutQueueFull is returned when no more fan-out jobs can be accepted.
+// Consumers should return it so the event is redelivered later.
+var ErrFanoutQueueFull = errors.New("fan-out queue is full")
+
+// RecipientPager returns up to limit recipients ordered by ID, starting
+// after the given ID (uuid.Nil for the first page)
+type RecipientPager func(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error)
+
+// FanoutJob sends one notification to every recipient returned by Recipients.
+// Sent, if set, is called for each recipient whose notification was sent.
+type FanoutJob struct {
+	Name       string
+	Recipients RecipientPager
+	Build      func(userID uuid.UUID) *CreateNotificationRequest
+	Sent       func(ctx context.Context, userID uuid.UUID) error
+}
+
+// FanoutService delivers notifications to large audiences in batches on
+// background workers, so consumers are not blocked by big follower lists.
+// Jobs are held in memory; a job interrupted by shutdown is not resumed.
+type FanoutService struct {
+	notifService *NotificationService
+	jobs         chan FanoutJob
+	batchSize    int
+	batchDelay   time.Duration
+	workers      int
+	log          *logrus.Logger
+	wg           sync.WaitGroup
+}
+
+func NewFanoutService(notifService *NotificationService, batchSize, queueSize, workers int, batchDelay time.Duration, log *logrus.Logger) *FanoutService {
+	return &FanoutService{
+		notifService: notifService,
+		jobs:         make(chan FanoutJob, queueSize),
+		batchSize:    batchSize,
+		batchDelay:   batchDelay,
+		workers:      workers,
+		log:          log,
+	}
+}
+
+// Start launches the fan-out workers; they stop when ctx is cancelled
+func (f *FanoutService) Start(ctx context.Context) {
+	for i := 0; i < f.workers; i++ {
+		f.wg.Add(1)
+		go func() {
+			defer f.wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-f.jobs:
+					f.run(ctx, job)
+				}
+			}
+		}()
+	}
+}
+
+// Wait blocks until every worker has stopped
+func (f *FanoutService) Wait() {
+	f.wg.Wait()
+}
+
+// Enqueue schedules a job without blocking
+func (f *FanoutService) Enqueue(job FanoutJob) error {
+	select {
+	case f.jobs <- job:
+		return nil
+	default:
+		return ErrFanoutQueueFull
+	}
+}
+
+func (f *FanoutService) run(ctx context.Context, job FanoutJob) {
+	log := f.log.WithField("job", job.Name)
+	log.Info("Starting fan-out")
+
+	after := uuid.Nil
+	sent, failed := 0, 0
+
+	for {
+		recipients, err := job.Recipients(ctx, after, f.batchSize)
+		if err != nil {
+			log.WithError(err).Error("Failed to load fan-out recipients, aborting")
+			return
+		}
+
+		for _, userID := range recipients {
+			if err := f.notifService.CreateAndSendNotification(ctx, job.Build(userID)); err != nil {
+				log.WithError(err).WithField("user_id", userID).Warn("Fan-out notification failed")
+				failed++
+				continue
+			}
+			sent++
+
+			if job.Sent != nil {
+				if err := job.Sent(ctx, userID); err != nil {
+					log.WithError(err).WithField("user_id", userID).Warn("Fan-out sent hook failed")
+				}
+			}
+		}
+
+		if len(recipients) < f.batchSize {
+			break
+		}
+		after = recipients[len(recipients)-1]
+
+		select {
+		case <-ctx.Done():
+			log.WithField("sent", sent).Warn("Fan-out interrupted by shutdown")
+			return
+		case <-time.After(f.batchDelay):
+		}
+	}
+
+	log.WithFields(logrus.Fields{
+		"sent":   sent,
+		"failed": failed,
+	}).Info("Fan-out complete")
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/entities"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/repositories"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// FollowupHandler performs a due follow-up
+type FollowupHandler func(ctx context.Context, followup entities.OrderFollowup) error
+
+const (
+	followupMaxAttempts = 3
+	followupRetryDelay  = 5 * time.Minute
+)
+
+// FollowupScheduler polls Postgres for due order follow-ups and runs the
+// handler registered for their kind
+type FollowupScheduler struct {
+	poller
+	repo     *repositories.FollowupRepository
+	handlers map[string]FollowupHandler
+}
+
+func NewFollowupScheduler(repo *repositories.FollowupRepository, interval time.Duration, batchSize int, log *logrus.Logger) *FollowupScheduler {
+	return &FollowupScheduler{
+		poller: poller{
+			name:        "Followup",
+			queue:       repo,
+			interval:    interval,
+			batchSize:   batchSize,
+			maxAttempts: followupMaxAttempts,
+			retryDelay:  followupRetryDelay,
+			log:         log,
+		},
+		repo:     repo,
+		handlers: make(map[string]FollowupHandler),
+	}
+}
+
+// Register sets the handler for a follow-up kind. It must be called before Start.
+func (s *FollowupScheduler) Register(kind string, handler FollowupHandler) {
+	s.handlers[kind] = handler
+}
+
+// Schedule stores a follow-up to run at f.DueAt
+func (s *FollowupScheduler) Schedule(ctx context.Context, f *entities.OrderFollowup) error {
+	if _, ok := s.handlers[f.Kind]; !ok {
+		return fmt.Errorf("no handler registered for followup kind %q", f.Kind)
+	}
+	return s.repo.Schedule(ctx, f)
+}
+
+// Cancel cancels pending follow-ups of the given kinds for an order
+func (s *FollowupScheduler) Cancel(ctx context.Context, orderID string, userID uuid.UUID, kinds ...string) error {
+	cancelled, err := s.repo.Cancel(ctx, orderID, userID, kinds)
+	if err != nil {
+		return err
+	}
+
+	if cancelled > 0 {
+		s.log.WithFields(logrus.Fields{
+			"order_id":  orderID,
+			"kinds":     kinds,
+			"cancelled": cancelled,
+		}).Info("Cancelled pending followups")
+	}
+	return nil
+}
+
+// Start polls for due follow-ups until ctx is cancelled
+func (s *FollowupScheduler) Start(ctx context.Context) {
+	s.start(ctx, s.runDue)
+}
+
+func (s *FollowupScheduler) runDue(ctx context.Context) {
+	followups, err := s.repo.ClaimDue(ctx, s.batchSize, jobStaleAfter)
+	if err != nil {
+		s.log.WithError(err).Error("Failed to claim due followups")
+		return
+	}
+
+	for _, f := range followups {
+		log := s.log.WithFields(logrus.Fields{
+			"followup_id": f.ID,
+			"order_id":    f.OrderID,
+			"kind":        f.Kind,
+			"attempt":     f.Attempts,
+		})
+
+		handler, ok := s.handlers[f.Kind]
+		if !ok {
+			log.Error("No handler for followup kind")
+			s.abandon(ctx, log, f.ID, "no handler registered")
+			continue
+		}
+
+		s.finish(ctx, log, f.ID, f.Attempts, handler(ctx, f))
+	}
+}
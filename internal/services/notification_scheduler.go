@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/entities"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/repositories"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	scheduledMaxAttempts = 3
+	scheduledRetryDelay  = time.Minute
+)
+
+// NotificationScheduler polls Postgres for scheduled notifications that are
+// due and sends them
+type NotificationScheduler struct {
+	poller
+	repo         *repositories.ScheduledNotificationRepository
+	notifService *NotificationService
+}
+
+func NewNotificationScheduler(repo *repositories.ScheduledNotificationRepository, notifService *NotificationService, interval time.Duration, batchSize int, log *logrus.Logger) *NotificationScheduler {
+	return &NotificationScheduler{
+		poller: poller{
+			name:        "Scheduled notification",
+			queue:       repo,
+			interval:    interval,
+			batchSize:   batchSize,
+			maxAttempts: scheduledMaxAttempts,
+			retryDelay:  scheduledRetryDelay,
+			log:         log,
+		},
+		repo:         repo,
+		notifService: notifService,
+	}
+}
+
+// Start polls for due notifications until ctx is cancelled
+func (s *NotificationScheduler) Start(ctx context.Context) {
+	s.start(ctx, s.runDue)
+}
+
+func (s *NotificationScheduler) runDue(ctx context.Context) {
+	due, err := s.repo.ClaimDue(ctx, s.batchSize, jobStaleAfter)
+	if err != nil {
+		s.log.WithError(err).Error("Failed to claim due scheduled notifications")
+		return
+	}
+
+	for _, n := range due {
+		log := s.log.WithFields(logrus.Fields{
+			"scheduled_id": n.ID,
+			"user_id":      n.UserID,
+			"attempt":      n.Attempts,
+		})
+		s.finish(ctx, log, n.ID, n.Attempts, s.send(ctx, n))
+	}
+}
+
+func (s *NotificationScheduler) send(ctx context.Context, n entities.ScheduledNotification) error {
+	var req CreateNotificationRequest
+	if err := json.Unmarshal(n.Request, &req); err != nil {
+		return fmt.Errorf("failed to unmarshal notification request: %w", err)
+	}
+	return s.notifService.CreateAndSendNotification(ctx, &req)
+}
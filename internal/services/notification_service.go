@@ -3,99 +3,222 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/entities"
 	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/repositories"
 	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/senders"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/templates"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
 type NotificationService struct {
 	repo        *repositories.NotificationRepository
-	emailSender senders.Sender
-	pushSender  senders.Sender
+	scheduled   *repositories.ScheduledNotificationRepository
+	prefs       *PreferenceFilter
+	senders     *senders.Registry
+	templates   *templates.Registry
+	emailLayout *templates.EmailLayout
+	parallelism int
+	deadline    time.Duration
 	log         *logrus.Logger
 }
 
-func NewNotificationService(repo *repositories.NotificationRepository, emailSender, pushSender senders.Sender, log *logrus.Logger) *NotificationService {
+// NewNotificationService creates the service. Up to parallelism sends of a
+// notification run at once, and all of them must finish within deadline
+// (0 for none).
+func NewNotificationService(repo *repositories.NotificationRepository, scheduled *repositories.ScheduledNotificationRepository, prefs *PreferenceFilter, senderRegistry *senders.Registry, tmplRegistry *templates.Registry, emailLayout *templates.EmailLayout, parallelism int, deadline time.Duration, log *logrus.Logger) *NotificationService {
 	return &NotificationService{
 		repo:        repo,
-		emailSender: emailSender,
-		pushSender:  pushSender,
+		scheduled:   scheduled,
+		prefs:       prefs,
+		senders:     senderRegistry,
+		templates:   tmplRegistry,
+		emailLayout: emailLayout,
+		parallelism: parallelism,
+		deadline:    deadline,
 		log:         log,
 	}
 }
 
-// CreateNotificationRequest represents notification creation request
+// CreateNotificationRequest represents notification creation request.
+// When Template is set, Title and Message are rendered from the template
+// using Metadata as variables; otherwise they are sent verbatim.
+// Variants override the subject/body/preview for individual channels.
+// Action and Summary are only used by the HTML email layout.
+// A SendAt in the future stores the request and sends it when due.
+// CollapseKey replaces the user's previous notification with the same key in
+// the inbox and push tray instead of adding a new one. With EventTime set, a
+// notification for an older event than the stored one is dropped.
+// Channels the user opted out of are dropped and notifications arriving in
+// quiet hours are deferred, unless Priority is PriorityCritical.
+// Redact lists Metadata keys (e.g. OTP codes) that are only used to render
+// the content and are neither stored nor passed to senders.
+// MetadataFields, when not nil, lists the only Metadata keys that are stored
+// and passed to senders; the others (contact details, full event payloads)
+// are only used to render and address the notification.
+// Sends deferred while a provider's circuit is open are redelivered later
+// with NotificationID and Providers set: the stored notification is reused
+// and only the named providers are sent through.
 type CreateNotificationRequest struct {
-	UserID   string
-	Type     string
-	Category string
-	Title    string
-	Message  string
-	Channels []string
-	Metadata map[string]interface{}
+	UserID      string                              `json:"user_id"`
+	Type        string                              `json:"type"`
+	Category    string                              `json:"category"`
+	Template    string                              `json:"template,omitempty"`
+	Title       string                              `json:"title,omitempty"`
+	Message     string                              `json:"message,omitempty"`
+	Variants    map[string]templates.ChannelContent `json:"variants,omitempty"`
+	Channels    []string                            `json:"channels"`
+	Priority    string                              `json:"priority,omitempty"`
+	Metadata    map[string]interface{}              `json:"metadata,omitempty"`
+	Action      *templates.EmailAction              `json:"action,omitempty"`
+	Summary     []templates.EmailSummaryRow         `json:"summary,omitempty"`
+	CollapseKey string                              `json:"collapse_key,omitempty"`
+	EventTime   time.Time                           `json:"event_time,omitzero"`
+	Redact      []string                            `json:"redact,omitempty"`
+	SendAt      time.Time                           `json:"-"`
+
+	// Not omitempty: an empty list stores no metadata, unlike nil
+	MetadataFields []string `json:"metadata_fields"`
+
+	NotificationID string   `json:"notification_id,omitempty"`
+	Providers      []string `json:"providers,omitempty"`
 }
 
-// CreateAndSendNotification creates notification and sends via configured channels
+// CreateAndSendNotification creates notification and sends via configured
+// channels concurrently. The notification is failed if any channel failed.
 func (s *NotificationService) CreateAndSendNotification(ctx context.Context, req *CreateNotificationRequest) error {
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid user_id %q: %w", req.UserID, err)
+	}
+
+	content, err := s.renderContent(req)
+	if err != nil {
+		return err
+	}
+
+	if req.SendAt.After(time.Now()) {
+		return s.schedule(ctx, req)
+	}
+
+	channels := req.Channels
+	if s.prefs != nil && req.Priority != PriorityCritical {
+		allowed, quietUntil, err := s.prefs.Apply(ctx, userID, req.Type, req.Channels, time.Now())
+		switch {
+		case err != nil:
+			s.log.WithError(err).Warn("Failed to load preferences, using requested channels")
+		case len(allowed) == 0:
+			s.log.WithFields(logrus.Fields{
+				"user_id":  req.UserID,
+				"type":     req.Type,
+				"category": req.Category,
+			}).Info("All channels disabled by user preferences, skipping notification")
+			return nil
+		case !quietUntil.IsZero() && len(req.Redact) == 0:
+			// Preferences are checked again when the deferred notification is
+			// sent. Redacted notifications cannot be stored, so they are sent
+			// despite quiet hours.
+			deferred := *req
+			deferred.SendAt = quietUntil
+			return s.schedule(ctx, &deferred)
+		default:
+			channels = allowed
+		}
+	}
+
+	notificationID := uuid.New()
+	redelivery := req.NotificationID != ""
+	if redelivery {
+		notificationID, err = uuid.Parse(req.NotificationID)
+		if err != nil {
+			return fmt.Errorf("invalid notification_id %q: %w", req.NotificationID, err)
+		}
+	}
+
+	// The stored notification is what the in-app inbox shows
+	inApp := content.For("in_app")
+
 	s.log.WithFields(logrus.Fields{
 		"user_id":  req.UserID,
 		"type":     req.Type,
 		"category": req.Category,
-		"title":    req.Title,
+		"template": req.Template,
+		"title":    inApp.Subject,
 	}).Info("Creating notification")
 
 	// Create notification entity
 	notification := &entities.Notification{
-		ID:       uuid.New(),
-		UserID:   uuid.MustParse(req.UserID),
+		ID:       notificationID,
+		UserID:   userID,
 		Type:     req.Type,
 		Category: req.Category,
-		Title:    req.Title,
-		Message:  req.Message,
-		Metadata: req.Metadata,
-		Channels: req.Channels,
+		Title:    inApp.Subject,
+		Message:  inApp.Body,
+		Metadata: req.storedMetadata(),
+		Channels: channels,
+		Priority: req.Priority,
 		Status:   "processing",
+
+		CollapseKey: req.CollapseKey,
+	}
+	if !req.EventTime.IsZero() {
+		notification.EventTime = &req.EventTime
 	}
 
-	// Save to database
-	if s.repo != nil {
-		if err := s.repo.Create(ctx, notification); err != nil {
+	// Save to database, unless this redelivers a stored notification
+	if s.repo != nil && !redelivery {
+		err := s.repo.Create(ctx, notification)
+		switch {
+		case errors.Is(err, repositories.ErrStaleNotification):
+			// A newer update already replaced it, e.g. a late in_transit
+			// after delivered; sending it would show outdated news
+			s.log.WithFields(logrus.Fields{
+				"user_id":      req.UserID,
+				"collapse_key": req.CollapseKey,
+				"event_time":   req.EventTime,
+			}).Info("Skipping notification older than the one it would replace")
+			return nil
+		case err != nil:
 			s.log.WithError(err).Error("Failed to save notification to database")
 			// Continue even if DB save fails (notification still sent)
 		}
 	}
 
 	// Send via channels
-	for _, channel := range req.Channels {
-		switch channel {
-		case "email":
-			if err := s.sendEmail(ctx, req, notification); err != nil {
-				s.log.WithError(err).Error("Failed to send email")
-				notification.Status = "failed"
-			}
-		case "push":
-			if err := s.sendPush(ctx, req, notification); err != nil {
-				s.log.WithError(err).Error("Failed to send push")
-				notification.Status = "failed"
-			}
-		case "in_app":
-			// In-app already saved to DB
-			s.log.Info("In-app notification saved")
+	deferred := &deferral{}
+	for _, result := range s.dispatch(ctx, channels, req, content, notification) {
+		if err := s.settle(ctx, notification, result, deferred); err != nil {
+			s.log.WithError(err).WithField("channel", result.channel).Error("Failed to send notification")
+			notification.Status = "failed"
+		}
+	}
+
+	if len(deferred.channels) > 0 {
+		if err := s.redeliver(ctx, req, notification, deferred); err != nil {
+			s.log.WithError(err).Error("Failed to schedule deferred deliveries")
+			notification.Status = "failed"
+		} else if notification.Status != "failed" {
+			notification.Status = "deferred"
 		}
 	}
 
-	if notification.Status != "failed" {
+	if notification.Status == "processing" {
 		notification.Status = "sent"
 	}
 
 	// Update status in database
 	if s.repo != nil {
-		if err := s.repo.UpdateStatus(ctx, notification.ID, notification.Status); err != nil {
+		update := s.repo.UpdateStatus
+		if redelivery {
+			update = s.repo.UpdateDeferredStatus
+		}
+		if err := update(ctx, notification.ID, notification.Status); err != nil {
 			s.log.WithError(err).Warn("Failed to update notification status")
 		}
 	}
@@ -103,71 +226,487 @@ func (s *NotificationService) CreateAndSendNotification(ctx context.Context, req
 	return nil
 }
 
-func (s *NotificationService) sendEmail(ctx context.Context, req *CreateNotificationRequest, notif *entities.Notification) error {
-	payload := senders.NotificationPayload{
-		To:      req.UserID, // In real: get user email from DB
-		Subject: req.Title,
-		Body:    s.formatEmailBody(req),
-		Data:    req.Metadata,
-	}
+// IsOpsChannel reports whether channel is one of the chats reserved for ops
+// alerts
+func IsOpsChannel(channel string) bool {
+	return channel == "slack" || channel == "telegram"
+}
 
-	if err := s.emailSender.Send(ctx, payload); err != nil {
-		return fmt.Errorf("email send failed: %w", err)
+// SendOpsAlert posts a notification to the internal ops chats named in
+// req.Channels ("slack", "telegram"). UserID is ignored: alerts are neither
+// stored nor subject to preferences.
+func (s *NotificationService) SendOpsAlert(ctx context.Context, req *CreateNotificationRequest) error {
+	content, err := s.renderContent(req)
+	if err != nil {
+		return err
 	}
 
-	// Update email_sent_at in DB
-	if s.repo != nil {
-		if err := s.repo.UpdateEmailSentAt(ctx, notif.ID); err != nil {
-			s.log.WithError(err).Warn("Failed to update email sent timestamp")
+	s.log.WithFields(logrus.Fields{
+		"type":     req.Type,
+		"category": req.Category,
+		"template": req.Template,
+		"channels": req.Channels,
+	}).Info("Sending ops alert")
+
+	var errs []error
+	for _, channel := range req.Channels {
+		if !IsOpsChannel(channel) {
+			errs = append(errs, fmt.Errorf("unknown ops channel %q", channel))
+			continue
+		}
+		channelSenders := s.senders.Get(channel)
+		if len(channelSenders) == 0 {
+			errs = append(errs, fmt.Errorf("no sender registered for channel %q", channel))
+			continue
 		}
+
+		chat := content.For(channel)
+		payload := senders.NotificationPayload{
+			Type:     req.Type,
+			Category: req.Category,
+			Subject:  chat.Subject,
+			Body:     opsAlertBody(chat.Body, req.Summary, req.Action),
+			Priority: req.Priority,
+			Data:     req.storedMetadata(),
+		}
+		for _, sender := range channelSenders {
+			if err := sender.Send(ctx, payload); err != nil {
+				s.log.WithError(err).WithField("channel", channel).Error("Failed to send ops alert")
+				errs = append(errs, fmt.Errorf("%s send failed: %w", channel, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// opsAlertBody appends the summary rows and action link to the message, as
+// chats have no email layout
+func opsAlertBody(message string, summary []templates.EmailSummaryRow, action *templates.EmailAction) string {
+	var b strings.Builder
+	b.WriteString(message)
+	if len(summary) > 0 {
+		b.WriteString("\n")
+	}
+	for _, row := range summary {
+		fmt.Fprintf(&b, "\n%s: %s", row.Label, row.Value)
+	}
+	if action != nil {
+		fmt.Fprintf(&b, "\n\n%s: %s", action.Label, action.URL)
+	}
+	return b.String()
+}
+
+// errNotSchedulable is returned for requests that must be sent right away
+// or not at all
+var errNotSchedulable = errors.New("critical and redacted notifications cannot be scheduled")
+
+// schedule persists a request for the scheduler to send at req.SendAt. The
+// content is rendered beforehand so broken requests fail when scheduled.
+// Critical and redacted requests (OTPs, security alerts) are refused: the
+// stored request would hold the redacted metadata, and they are useless
+// when late.
+func (s *NotificationService) schedule(ctx context.Context, req *CreateNotificationRequest) error {
+	if req.Priority == PriorityCritical || len(req.Redact) > 0 {
+		return errNotSchedulable
+	}
+	if s.scheduled == nil {
+		return fmt.Errorf("notification scheduled for %s but no scheduled notification store configured", req.SendAt)
+	}
+
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid user_id %q: %w", req.UserID, err)
+	}
+
+	requestJSON, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification request: %w", err)
 	}
 
+	scheduled := &entities.ScheduledNotification{
+		ID:      uuid.New(),
+		UserID:  userID,
+		SendAt:  req.SendAt,
+		Request: requestJSON,
+	}
+	if err := s.scheduled.Create(ctx, scheduled); err != nil {
+		return err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"scheduled_id": scheduled.ID,
+		"user_id":      req.UserID,
+		"category":     req.Category,
+		"send_at":      req.SendAt,
+	}).Info("Notification scheduled")
 	return nil
 }
 
-func (s *NotificationService) sendPush(ctx context.Context, req *CreateNotificationRequest, notif *entities.Notification) error {
-	payload := senders.NotificationPayload{
-		To:      req.UserID, // In real: get FCM token from DB
-		Subject: req.Title,
-		Body:    req.Message,
-		Data:    req.Metadata,
+// renderContent resolves the content, rendering the template if one is set
+// and applying request variants on top
+func (s *NotificationService) renderContent(req *CreateNotificationRequest) (templates.Content, error) {
+	content := templates.Content{
+		Title:   req.Title,
+		Message: req.Message,
 	}
 
-	if err := s.pushSender.Send(ctx, payload); err != nil {
-		return fmt.Errorf("push send failed: %w", err)
+	if req.Template != "" {
+		if s.templates == nil {
+			return templates.Content{}, fmt.Errorf("template %q requested but no template registry configured", req.Template)
+		}
+
+		rendered, err := s.templates.Render(req.Template, req.Metadata)
+		if err != nil {
+			return templates.Content{}, fmt.Errorf("failed to render template: %w", err)
+		}
+		content = rendered
 	}
 
-	// Update push_sent_at in DB
-	if s.repo != nil {
-		if err := s.repo.UpdatePushSentAt(ctx, notif.ID); err != nil {
-			s.log.WithError(err).Warn("Failed to update push sent timestamp")
+	return content.WithVariants(req.Variants), nil
+}
+
+// errNoPhoneNumber skips phone channels for users without a phone number
+var errNoPhoneNumber = errors.New("no phone number in notification data")
+
+// errNoEmailAddress skips email for users without an email address
+var errNoEmailAddress = errors.New("no email address in notification data")
+
+// channelSend is one send of a notification through one of a channel's
+// senders
+type channelSend struct {
+	sender   senders.Sender
+	payload  senders.NotificationPayload
+	provider string
+	err      error
+}
+
+// channelResult collects the sends of one channel. err is set when the
+// channel could not be sent at all.
+type channelResult struct {
+	channel string
+	sends   []*channelSend
+	err     error
+}
+
+// dispatch sends the notification on every channel but in_app, running the
+// sends of all channels concurrently. At most s.parallelism sends run at
+// once and all of them share the per-notification deadline.
+func (s *NotificationService) dispatch(ctx context.Context, channels []string, req *CreateNotificationRequest, content templates.Content, notif *entities.Notification) []*channelResult {
+	var results []*channelResult
+	var sends []*channelSend
+	for _, channel := range channels {
+		if channel == "in_app" {
+			// In-app already saved to DB
+			s.log.Info("In-app notification saved")
+			continue
 		}
+
+		result := &channelResult{channel: channel}
+		result.sends, result.err = s.prepare(channel, req, content, notif)
+		results = append(results, result)
+		sends = append(sends, result.sends...)
+	}
+
+	if s.deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.deadline)
+		defer cancel()
 	}
 
+	errs := senders.ForEach(ctx, len(sends), s.parallelism, func(i int) error {
+		var err error
+		sends[i].provider, err = senders.SendVia(ctx, sends[i].sender, sends[i].payload)
+		return err
+	})
+	for i, send := range sends {
+		send.err = errs[i]
+	}
+
+	return results
+}
+
+// prepare builds the sends of one channel: its payload through every
+// sender registered for it, or those named in req.Providers. Channels
+// without a sender are reported as errors.
+func (s *NotificationService) prepare(channel string, req *CreateNotificationRequest, content templates.Content, notif *entities.Notification) ([]*channelSend, error) {
+	if IsOpsChannel(channel) {
+		return nil, fmt.Errorf("channel %q is reserved for ops alerts", channel)
+	}
+
+	channelSenders := s.channelSenders(channel, req.Providers)
+	if len(channelSenders) == 0 {
+		return nil, fmt.Errorf("no sender registered for channel %q", channel)
+	}
+
+	payload, err := s.payload(channel, req, content, notif)
+	if errors.Is(err, errNoPhoneNumber) || errors.Is(err, errNoEmailAddress) {
+		s.log.WithError(err).WithFields(logrus.Fields{
+			"user_id": notif.UserID,
+			"channel": channel,
+		}).Warn("No recipient address, skipping channel")
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sends := make([]*channelSend, 0, len(channelSenders))
+	for _, sender := range channelSenders {
+		sends = append(sends, &channelSend{
+			sender:   sender,
+			payload:  payload,
+			provider: senders.ProviderName(sender),
+		})
+	}
+	return sends, nil
+}
+
+// settle records the outcome of a channel's sends and returns its error.
+// A channel only fails when none of its senders delivered, e.g. push fails
+// only when no device accepted it; partial deliveries and senders without a
+// target (no devices) are recorded but do not fail it. Sends refused by an
+// open circuit are added to deferred instead.
+func (s *NotificationService) settle(ctx context.Context, notif *entities.Notification, result *channelResult, deferred *deferral) error {
+	if result.err != nil {
+		return result.err
+	}
+
+	var errs []error
+	sent := 0
+	for _, send := range result.sends {
+		s.recordDelivery(ctx, notif, result.channel, send.provider, send.err)
+		if retryAt, ok := senders.DeferredUntil(send.err); ok {
+			deferred.add(result.channel, senders.ProviderName(send.sender), retryAt)
+			continue
+		}
+		switch {
+		case errors.Is(send.err, senders.ErrNoTarget):
+		case send.err != nil && !senders.IsPartial(send.err):
+			errs = append(errs, fmt.Errorf("%s send failed: %w", result.channel, send.err))
+		default:
+			sent++
+		}
+	}
+
+	if sent == 0 {
+		return errors.Join(errs...)
+	}
+	if len(errs) > 0 {
+		s.log.WithError(errors.Join(errs...)).WithFields(logrus.Fields{
+			"notification_id": notif.ID,
+			"channel":         result.channel,
+		}).Warn("Some senders of the channel failed")
+	}
+
+	s.markSent(ctx, result.channel, notif)
 	return nil
 }
 
-func (s *NotificationService) formatEmailBody(req *CreateNotificationRequest) string {
-	// Simple template replacement
-	body := req.Message
+// channelSenders returns the senders of channel, limited to the named
+// providers if any are given
+func (s *NotificationService) channelSenders(channel string, providers []string) []senders.Sender {
+	channelSenders := s.senders.Get(channel)
+	if len(providers) == 0 {
+		return channelSenders
+	}
+
+	var named []senders.Sender
+	for _, sender := range channelSenders {
+		if slices.Contains(providers, senders.ProviderName(sender)) {
+			named = append(named, sender)
+		}
+	}
+	return named
+}
+
+// deferral collects the sends refused by open provider circuits, to be
+// redelivered together once the last of those circuits is due to close
+type deferral struct {
+	channels  []string
+	providers []string
+	retryAt   time.Time
+}
+
+func (d *deferral) add(channel, provider string, retryAt time.Time) {
+	if !slices.Contains(d.channels, channel) {
+		d.channels = append(d.channels, channel)
+	}
+	d.providers = append(d.providers, provider)
+	if retryAt.After(d.retryAt) {
+		d.retryAt = retryAt
+	}
+}
+
+// redeliver schedules the deferred sends of notif through the scheduler
+func (s *NotificationService) redeliver(ctx context.Context, req *CreateNotificationRequest, notif *entities.Notification, deferred *deferral) error {
+	redelivery := *req
+	redelivery.NotificationID = notif.ID.String()
+	redelivery.Channels = deferred.channels
+	redelivery.Providers = deferred.providers
+	redelivery.SendAt = deferred.retryAt
+
+	s.log.WithFields(logrus.Fields{
+		"notification_id": notif.ID,
+		"channels":        deferred.channels,
+		"providers":       deferred.providers,
+		"retry_at":        deferred.retryAt,
+	}).Warn("Provider circuit open, deferring delivery")
+
+	return s.schedule(ctx, &redelivery)
+}
+
+// recordDelivery stores which provider delivered, or last failed, on a channel
+func (s *NotificationService) recordDelivery(ctx context.Context, notif *entities.Notification, channel, provider string, sendErr error) {
+	if s.repo == nil {
+		return
+	}
 
-	// Replace variables from metadata
-	for key, value := range req.Metadata {
-		placeholder := fmt.Sprintf("{{%s}}", key)
-		body = strings.ReplaceAll(body, placeholder, fmt.Sprintf("%v", value))
+	delivery := &entities.NotificationDelivery{
+		ID:             uuid.New(),
+		NotificationID: notif.ID,
+		Channel:        channel,
+		Provider:       provider,
+		Status:         "sent",
+	}
+	if sendErr != nil {
+		delivery.Status = "failed"
+		delivery.Error = sendErr.Error()
+	}
+	switch {
+	case errors.Is(sendErr, senders.ErrNoTarget):
+		delivery.Status = "skipped"
+	case senders.IsPartial(sendErr):
+		delivery.Status = "partial"
+	default:
+		if _, ok := senders.DeferredUntil(sendErr); ok {
+			delivery.Status = "deferred"
+		}
 	}
 
-	return body
+	if err := s.repo.RecordDelivery(ctx, delivery); err != nil {
+		s.log.WithError(err).Warn("Failed to record delivery")
+	}
 }
 
-// Helper to interpolate template variables
-func (s *NotificationService) interpolateTemplate(template string, data map[string]interface{}) string {
-	result := template
-	for key, value := range data {
-		placeholder := fmt.Sprintf("{{%s}}", key)
-		result = strings.ReplaceAll(result, placeholder, fmt.Sprintf("%v", value))
+// payload builds what the senders of a channel receive: the channel's
+// variant of the content and provider template, plus the address and HTML
+// layout for email and the phone number for sms and whatsapp
+func (s *NotificationService) payload(channel string, req *CreateNotificationRequest, content templates.Content, notif *entities.Notification) (senders.NotificationPayload, error) {
+	variant := content.For(channel)
+	if channel == "push" {
+		variant = templates.PushLimits.Apply(variant)
+	}
+
+	payload := senders.NotificationPayload{
+		NotificationID: notif.ID.String(),
+		Type:           notif.Type,
+		Category:       notif.Category,
+		To:             notif.UserID.String(),
+		Subject:        variant.Subject,
+		Body:           variant.Body,
+		Priority:       notif.Priority,
+		Data:           notif.Metadata,
+
+		CollapseKey: notif.CollapseKey,
+	}
+	if provider := content.ProviderTemplate(channel); provider != nil {
+		payload.Template = &senders.TemplateMessage{
+			Name:       provider.Name,
+			Language:   provider.Language,
+			Parameters: provider.Parameters,
+		}
+	}
+
+	switch channel {
+	case "email":
+		payload.To = emailAddress(req)
+		if payload.To == "" {
+			return payload, errNoEmailAddress
+		}
+		if s.emailLayout != nil {
+			html, text, err := s.emailLayout.Render(templates.EmailContent{
+				Subject: variant.Subject,
+				Preview: variant.Preview,
+				Message: variant.Body,
+				Action:  req.Action,
+				Summary: req.Summary,
+			})
+			if err != nil {
+				return payload, fmt.Errorf("email render failed: %w", err)
+			}
+			payload.Body = text
+			payload.HTMLBody = html
+		}
+	case "sms", "whatsapp":
+		payload.To = phoneNumber(req)
+		if payload.To == "" {
+			return payload, errNoPhoneNumber
+		}
+	}
+
+	return payload, nil
+}
+
+// markSent records delivery for the channels the notification row tracks
+func (s *NotificationService) markSent(ctx context.Context, channel string, notif *entities.Notification) {
+	if s.repo == nil {
+		return
+	}
+
+	var err error
+	switch channel {
+	case "email":
+		err = s.repo.UpdateEmailSentAt(ctx, notif.ID)
+	case "push":
+		err = s.repo.UpdatePushSentAt(ctx, notif.ID)
+	}
+	if err != nil {
+		s.log.WithError(err).WithField("channel", channel).Warn("Failed to update sent timestamp")
+	}
+}
+
+// emailAddress returns the email carried in the request data
+func emailAddress(req *CreateNotificationRequest) string {
+	email, _ := req.Metadata["email"].(string) // In real: get email address from user service
+	return email
+}
+
+// phoneNumber returns the phone_number carried in the request data
+func phoneNumber(req *CreateNotificationRequest) string {
+	phone, _ := req.Metadata["phone_number"].(string) // In real: get phone number from user service
+	return phone
+}
+
+// storedMetadata returns the Metadata that is stored and passed to senders:
+// only the MetadataFields, if set, and never the redacted keys
+func (req *CreateNotificationRequest) storedMetadata() map[string]interface{} {
+	metadata := req.Metadata
+	if req.MetadataFields != nil {
+		metadata = make(map[string]interface{}, len(req.MetadataFields))
+		for _, key := range req.MetadataFields {
+			if value, ok := req.Metadata[key]; ok {
+				metadata[key] = value
+			}
+		}
+	}
+	return redactMetadata(metadata, req.Redact)
+}
+
+// redactMetadata returns metadata without the given keys
+func redactMetadata(metadata map[string]interface{}, keys []string) map[string]interface{} {
+	if len(keys) == 0 {
+		return metadata
+	}
+
+	redacted := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		redacted[key] = value
+	}
+	for _, key := range keys {
+		delete(redacted, key)
 	}
-	return result
+	return redacted
 }
 
 // Get metadata as JSON string for logging
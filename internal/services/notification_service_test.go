@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/entities"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/senders"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/templates"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestService() *NotificationService {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return NewNotificationService(nil, nil, nil, nil, nil, nil, 4, 0, log)
+}
+
+func TestPayloadRecipient(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name     string
+		channel  string
+		metadata map[string]interface{}
+		wantTo   string
+		wantErr  error
+	}{
+		{
+			name:     "email address from the data",
+			channel:  "email",
+			metadata: map[string]interface{}{"email": "budi@example.com"},
+			wantTo:   "budi@example.com",
+		},
+		{
+			name:    "email without an address",
+			channel: "email",
+			wantErr: errNoEmailAddress,
+		},
+		{
+			name:     "sms phone number from the data",
+			channel:  "sms",
+			metadata: map[string]interface{}{"phone_number": "081234567890"},
+			wantTo:   "081234567890",
+		},
+		{
+			name:    "sms without a phone number",
+			channel: "sms",
+			wantErr: errNoPhoneNumber,
+		},
+		{
+			name:    "push goes to the user",
+			channel: "push",
+			wantTo:  userID.String(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notif := &entities.Notification{ID: uuid.New(), UserID: userID}
+			content := templates.Content{Title: "Pesanan dikirim", Message: "Pesanan ORD-1 dikirim"}
+
+			payload, err := newTestService().payload(tt.channel, &CreateNotificationRequest{Metadata: tt.metadata}, content, notif)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("payload() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && payload.To != tt.wantTo {
+				t.Errorf("To = %q, want %q", payload.To, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestStoredMetadata(t *testing.T) {
+	metadata := map[string]interface{}{"order_id": "ORD-1", "email": "budi@example.com", "code": "123456"}
+
+	tests := []struct {
+		name   string
+		fields []string
+		redact []string
+		want   map[string]interface{}
+	}{
+		{
+			name: "everything without a list",
+			want: metadata,
+		},
+		{
+			name:   "only the listed fields",
+			fields: []string{"order_id", "tracking_number"},
+			want:   map[string]interface{}{"order_id": "ORD-1"},
+		},
+		{
+			name:   "an empty list stores nothing",
+			fields: []string{},
+			want:   map[string]interface{}{},
+		},
+		{
+			name:   "redacted fields are dropped from the list",
+			fields: []string{"order_id", "code"},
+			redact: []string{"code"},
+			want:   map[string]interface{}{"order_id": "ORD-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &CreateNotificationRequest{Metadata: metadata, MetadataFields: tt.fields, Redact: tt.redact}
+			if got := req.storedMetadata(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("storedMetadata() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// blockingSender sends on channel, returning err after delay or when ctx is
+// done, and tracks how many of its sends run at once
+type blockingSender struct {
+	channel string
+	delay   time.Duration
+	err     error
+	running *atomic.Int32
+	peak    *atomic.Int32
+}
+
+func (s *blockingSender) Send(ctx context.Context, payload senders.NotificationPayload) error {
+	n := s.running.Add(1)
+	defer s.running.Add(-1)
+	for {
+		p := s.peak.Load()
+		if n <= p || s.peak.CompareAndSwap(p, n) {
+			break
+		}
+	}
+
+	select {
+	case <-time.After(s.delay):
+		return s.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *blockingSender) GetType() string { return s.channel }
+
+func TestDispatch(t *testing.T) {
+	var running, peak atomic.Int32
+	sender := func(channel string, delay time.Duration, err error) senders.Sender {
+		return &blockingSender{channel: channel, delay: delay, err: err, running: &running, peak: &peak}
+	}
+
+	tests := []struct {
+		name        string
+		parallelism int
+		deadline    time.Duration
+		senders     []senders.Sender
+		wantErrs    map[string][]error
+	}{
+		{
+			name:        "every channel and sender",
+			parallelism: 2,
+			senders: []senders.Sender{
+				sender("push", 5*time.Millisecond, nil),
+				sender("push", 5*time.Millisecond, senders.ErrNoTarget),
+				sender("webhook", 5*time.Millisecond, nil),
+			},
+			wantErrs: map[string][]error{
+				"push":    {nil, senders.ErrNoTarget},
+				"webhook": {nil},
+			},
+		},
+		{
+			name:        "sends not started by the deadline fail with it",
+			parallelism: 1,
+			deadline:    20 * time.Millisecond,
+			senders: []senders.Sender{
+				sender("push", time.Second, nil),
+				sender("webhook", time.Millisecond, nil),
+			},
+			wantErrs: map[string][]error{
+				"push":    {context.DeadlineExceeded},
+				"webhook": {context.DeadlineExceeded},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			running.Store(0)
+			peak.Store(0)
+
+			s := newTestService()
+			s.senders = senders.NewRegistry(tt.senders...)
+			s.parallelism = tt.parallelism
+			s.deadline = tt.deadline
+
+			notif := &entities.Notification{ID: uuid.New(), UserID: uuid.New()}
+			content := templates.Content{Title: "Pesanan dikirim", Message: "Pesanan ORD-1 dikirim"}
+			results := s.dispatch(context.Background(), []string{"in_app", "push", "webhook"}, &CreateNotificationRequest{}, content, notif)
+
+			if len(results) != len(tt.wantErrs) {
+				t.Fatalf("got %d channel results, want %d", len(results), len(tt.wantErrs))
+			}
+			for _, result := range results {
+				want := tt.wantErrs[result.channel]
+				if len(result.sends) != len(want) {
+					t.Fatalf("%s: got %d sends, want %d", result.channel, len(result.sends), len(want))
+				}
+				for i, send := range result.sends {
+					if !errors.Is(send.err, want[i]) {
+						t.Errorf("%s send %d error = %v, want %v", result.channel, i, send.err, want[i])
+					}
+				}
+			}
+			if int(peak.Load()) > tt.parallelism {
+				t.Errorf("%d sends ran at once, parallelism %d", peak.Load(), tt.parallelism)
+			}
+		})
+	}
+}
+
+func TestSettle(t *testing.T) {
+	down := errors.New("provider unavailable")
+
+	tests := []struct {
+		name    string
+		errs    []error
+		wantErr bool
+	}{
+		{
+			name: "delivered",
+			errs: []error{nil},
+		},
+		{
+			name: "one sender delivered",
+			errs: []error{down, nil},
+		},
+		{
+			name:    "no sender delivered",
+			errs:    []error{down, down},
+			wantErr: true,
+		},
+		{
+			name: "no target is skipped, not failed",
+			errs: []error{senders.ErrNoTarget},
+		},
+		{
+			name: "partial delivery counts as delivered",
+			errs: []error{&senders.PartialError{Delivered: 1, Err: down}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &channelResult{channel: "push"}
+			for _, err := range tt.errs {
+				result.sends = append(result.sends, &channelSend{sender: &blockingSender{channel: "push"}, err: err})
+			}
+
+			err := newTestService().settle(context.Background(), &entities.Notification{ID: uuid.New()}, result, &deferral{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("settle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// jobStaleAfter is how long a claimed job may stay in processing before it
+// is assumed lost (e.g. to a crash) and claimed again
+const jobStaleAfter = 10 * time.Minute
+
+// jobQueue records the outcome of claimed jobs
+type jobQueue interface {
+	MarkSent(ctx context.Context, id uuid.UUID) error
+	Retry(ctx context.Context, id uuid.UUID, retryAt time.Time, lastErr string) error
+	MarkFailed(ctx context.Context, id uuid.UUID, lastErr string) error
+}
+
+// poller runs jobs claimed from a Postgres table on a ticker and records
+// their outcome. Failed jobs are retried after attempts * retryDelay, up to
+// maxAttempts.
+type poller struct {
+	name        string // for logs, e.g. "Followup"
+	queue       jobQueue
+	interval    time.Duration
+	batchSize   int
+	maxAttempts int
+	retryDelay  time.Duration
+	log         *logrus.Logger
+	wg          sync.WaitGroup
+}
+
+// start calls poll on every tick until ctx is cancelled
+func (p *poller) start(ctx context.Context, poll func(ctx context.Context)) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				poll(ctx)
+			}
+		}
+	}()
+}
+
+// Wait blocks until the poller has stopped
+func (p *poller) Wait() {
+	p.wg.Wait()
+}
+
+// finish records the outcome of a claimed job: sent, retried later or, once
+// attempts reaches maxAttempts, failed
+func (p *poller) finish(ctx context.Context, log *logrus.Entry, id uuid.UUID, attempts int, cause error) {
+	if cause == nil {
+		if err := p.queue.MarkSent(ctx, id); err != nil {
+			log.WithError(err).Warnf("Failed to mark %s sent", p.noun())
+		}
+		log.Infof("%s sent", p.name)
+		return
+	}
+
+	if attempts >= p.maxAttempts {
+		log.WithError(cause).Errorf("%s failed, giving up", p.name)
+		p.abandon(ctx, log, id, cause.Error())
+		return
+	}
+
+	retryAt := time.Now().Add(time.Duration(attempts) * p.retryDelay)
+	log.WithError(cause).WithField("retry_at", retryAt).Warnf("%s failed, will retry", p.name)
+	if err := p.queue.Retry(ctx, id, retryAt, cause.Error()); err != nil {
+		log.WithError(err).Warnf("Failed to reschedule %s", p.noun())
+	}
+}
+
+// abandon marks a claimed job failed without retrying it
+func (p *poller) abandon(ctx context.Context, log *logrus.Entry, id uuid.UUID, reason string) {
+	if err := p.queue.MarkFailed(ctx, id, reason); err != nil {
+		log.WithError(err).Warnf("Failed to mark %s failed", p.noun())
+	}
+}
+
+// noun is the job name in the middle of a sentence
+func (p *poller) noun() string {
+	if p.name == "" {
+		return "job"
+	}
+	return strings.ToLower(p.name[:1]) + p.name[1:]
+}
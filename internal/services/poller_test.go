@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// recordingQueue remembers the last outcome recorded for a job
+type recordingQueue struct {
+	outcome string
+	retryAt time.Time
+	lastErr string
+}
+
+func (q *recordingQueue) MarkSent(ctx context.Context, id uuid.UUID) error {
+	q.outcome = "sent"
+	return nil
+}
+
+func (q *recordingQueue) Retry(ctx context.Context, id uuid.UUID, retryAt time.Time, lastErr string) error {
+	q.outcome, q.retryAt, q.lastErr = "retry", retryAt, lastErr
+	return nil
+}
+
+func (q *recordingQueue) MarkFailed(ctx context.Context, id uuid.UUID, lastErr string) error {
+	q.outcome, q.lastErr = "failed", lastErr
+	return nil
+}
+
+func TestPollerFinish(t *testing.T) {
+	const retryDelay = 5 * time.Minute
+	failure := errors.New("smtp unavailable")
+
+	tests := []struct {
+		name        string
+		attempts    int
+		cause       error
+		wantOutcome string
+		wantDelay   time.Duration
+	}{
+		{
+			name:        "sent",
+			attempts:    1,
+			wantOutcome: "sent",
+		},
+		{
+			name:        "first failure is retried",
+			attempts:    1,
+			cause:       failure,
+			wantOutcome: "retry",
+			wantDelay:   retryDelay,
+		},
+		{
+			name:        "retries back off with the attempts",
+			attempts:    2,
+			cause:       failure,
+			wantOutcome: "retry",
+			wantDelay:   2 * retryDelay,
+		},
+		{
+			name:        "gives up at the last attempt",
+			attempts:    3,
+			cause:       failure,
+			wantOutcome: "failed",
+		},
+	}
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := &recordingQueue{}
+			p := &poller{name: "Followup", queue: queue, maxAttempts: 3, retryDelay: retryDelay, log: log}
+
+			start := time.Now()
+			p.finish(context.Background(), logrus.NewEntry(log), uuid.New(), tt.attempts, tt.cause)
+
+			if queue.outcome != tt.wantOutcome {
+				t.Fatalf("outcome = %q, want %q", queue.outcome, tt.wantOutcome)
+			}
+			if tt.cause != nil && queue.lastErr != tt.cause.Error() {
+				t.Errorf("last error = %q, want %q", queue.lastErr, tt.cause.Error())
+			}
+			if tt.wantOutcome == "retry" {
+				if delay := queue.retryAt.Sub(start); delay < tt.wantDelay || delay > tt.wantDelay+time.Second {
+					t.Errorf("retried after %v, want %v", delay, tt.wantDelay)
+				}
+			}
+		})
+	}
+}
+
+func TestPollerNoun(t *testing.T) {
+	if got := (&poller{name: "Scheduled notification"}).noun(); got != "scheduled notification" {
+		t.Errorf("noun() = %q", got)
+	}
+	if got := (&poller{}).noun(); got != "job" {
+		t.Errorf("noun() = %q, want job", got)
+	}
+}
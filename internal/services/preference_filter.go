@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/entities"
+	"github.com/RehanAthallahAzhar/tokohobby-notifications/internal/repositories"
+	"github.com/google/uuid"
+)
+
+// PriorityCritical marks transactional notifications (security alerts, OTPs)
+// that ignore user preferences and quiet hours
+const PriorityCritical = "critical"
+
+// PreferenceFilter applies users' channel opt-outs and quiet hours
+type PreferenceFilter struct {
+	repo     *repositories.PreferenceRepository
+	location *time.Location
+}
+
+// NewPreferenceFilter creates a filter; quiet hours are interpreted in location
+func NewPreferenceFilter(repo *repositories.PreferenceRepository, location *time.Location) *PreferenceFilter {
+	return &PreferenceFilter{
+		repo:     repo,
+		location: location,
+	}
+}
+
+// Apply returns the channels the user accepts for a notification of the
+// given type and, when now falls in the user's quiet hours, the time they
+// end. Channels without a preference toggle are always kept, and users who
+// never saved preferences get every channel.
+func (f *PreferenceFilter) Apply(ctx context.Context, userID uuid.UUID, notifType string, channels []string, now time.Time) ([]string, time.Time, error) {
+	pref, err := f.repo.Get(ctx, userID)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if pref == nil {
+		return channels, time.Time{}, nil
+	}
+
+	global := map[string]bool{
+		"email":  pref.EmailEnabled,
+		"push":   pref.PushEnabled,
+		"in_app": pref.InAppEnabled,
+	}
+	category := categoryPreferences(pref, notifType)
+
+	allowed := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		if enabled, ok := global[channel]; ok && !enabled {
+			continue
+		}
+		if enabled, ok := category[channel]; ok && !enabled {
+			continue
+		}
+		allowed = append(allowed, channel)
+	}
+
+	return allowed, f.quietHoursEnd(pref, now), nil
+}
+
+func categoryPreferences(pref *entities.NotificationPreference, notifType string) map[string]bool {
+	switch notifType {
+	case "order", "seller_order":
+		return pref.OrderNotifications
+	case "account":
+		return pref.AccountNotifications
+	case "product":
+		return pref.ProductNotifications
+	default:
+		return nil
+	}
+}
+
+// quietHoursEnd returns when the quiet hours containing now end, or the zero
+// time if now is outside them. Windows may wrap midnight (22:00-07:00).
+func (f *PreferenceFilter) quietHoursEnd(pref *entities.NotificationPreference, now time.Time) time.Time {
+	if !pref.QuietHoursEnabled || pref.QuietHoursStart == nil || pref.QuietHoursEnd == nil {
+		return time.Time{}
+	}
+
+	local := now.In(f.location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, f.location)
+	start := midnight.Add(clockOffset(*pref.QuietHoursStart))
+	end := midnight.Add(clockOffset(*pref.QuietHoursEnd))
+
+	switch {
+	case start.Equal(end):
+		return time.Time{}
+	case start.Before(end):
+		if !local.Before(start) && local.Before(end) {
+			return end
+		}
+	default:
+		if !local.Before(start) {
+			return end.AddDate(0, 0, 1)
+		}
+		if local.Before(end) {
+			return end
+		}
+	}
+
+	return time.Time{}
+}
+
+func clockOffset(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
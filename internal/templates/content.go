@@ -0,0 +1,124 @@
+package templates
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ChannelContent is the channel-specific variant of a notification.
+// Empty fields fall back to the notification's title and message.
+type ChannelContent struct {
+	Subject string `json:"subject,omitempty"`
+	Body    string `json:"body,omitempty"`
+	Preview string `json:"preview,omitempty"`
+}
+
+// Content is a rendered notification with optional per-channel variants
+type Content struct {
+	Title             string
+	Message           string
+	Channels          map[string]ChannelContent
+	ProviderTemplates map[string]ProviderTemplate
+}
+
+// ProviderTemplate returns the rendered provider template for a channel, if any
+func (c Content) ProviderTemplate(channel string) *ProviderTemplate {
+	provider, ok := c.ProviderTemplates[channel]
+	if !ok {
+		return nil
+	}
+	return &provider
+}
+
+// For returns the content for a channel, filling gaps from the title and message
+func (c Content) For(channel string) ChannelContent {
+	variant := c.Channels[channel]
+	if variant.Subject == "" {
+		variant.Subject = c.Title
+	}
+	if variant.Body == "" {
+		variant.Body = c.Message
+	}
+	return variant
+}
+
+// WithVariants returns a copy of c where the given variants override
+// rendered ones field by field
+func (c Content) WithVariants(variants map[string]ChannelContent) Content {
+	if len(variants) == 0 {
+		return c
+	}
+
+	merged := make(map[string]ChannelContent, len(c.Channels)+len(variants))
+	for channel, variant := range c.Channels {
+		merged[channel] = variant
+	}
+	for channel, override := range variants {
+		variant := merged[channel]
+		if override.Subject != "" {
+			variant.Subject = override.Subject
+		}
+		if override.Body != "" {
+			variant.Body = override.Body
+		}
+		if override.Preview != "" {
+			variant.Preview = override.Preview
+		}
+		merged[channel] = variant
+	}
+
+	c.Channels = merged
+	return c
+}
+
+// ChannelLimits caps the length, in characters, of channel content.
+// A zero limit means unlimited.
+type ChannelLimits struct {
+	SubjectMax int
+	BodyMax    int
+	PreviewMax int
+}
+
+// PushLimits keeps push content within what iOS and Android show on the
+// lock screen without clipping mid-word
+var PushLimits = ChannelLimits{
+	SubjectMax: 65,
+	BodyMax:    178,
+}
+
+// Apply truncates every field of variant to the limits
+func (l ChannelLimits) Apply(variant ChannelContent) ChannelContent {
+	variant.Subject = Truncate(variant.Subject, l.SubjectMax)
+	variant.Body = Truncate(variant.Body, l.BodyMax)
+	variant.Preview = Truncate(variant.Preview, l.PreviewMax)
+	return variant
+}
+
+const ellipsis = "…"
+
+// Truncate shortens text to at most max characters, cutting at a word
+// boundary when one is reasonably close and appending an ellipsis
+func Truncate(text string, max int) string {
+	if max <= 0 || utf8.RuneCountInString(text) <= max {
+		return text
+	}
+
+	runes := []rune(text)
+	cut := max - utf8.RuneCountInString(ellipsis)
+	if cut <= 0 {
+		return string(runes[:max])
+	}
+
+	// Prefer breaking on whitespace within the last quarter of the allowance
+	for i := cut; i > cut*3/4; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+
+	return strings.TrimRightFunc(string(runes[:cut]), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + ellipsis
+}
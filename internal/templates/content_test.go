@@ -0,0 +1,141 @@
+package templates
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestContentFor(t *testing.T) {
+	content := Content{
+		Title:   "Pesanan dikirim",
+		Message: "Pesanan ORD-1 sudah dikirim",
+		Channels: map[string]ChannelContent{
+			"sms": {Body: "ORD-1 dikirim"},
+		},
+	}
+
+	tests := []struct {
+		channel string
+		want    ChannelContent
+	}{
+		{
+			channel: "sms",
+			want:    ChannelContent{Subject: "Pesanan dikirim", Body: "ORD-1 dikirim"},
+		},
+		{
+			channel: "email",
+			want:    ChannelContent{Subject: "Pesanan dikirim", Body: "Pesanan ORD-1 sudah dikirim"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.channel, func(t *testing.T) {
+			if got := content.For(tt.channel); got != tt.want {
+				t.Errorf("For(%q) = %+v, want %+v", tt.channel, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentWithVariants(t *testing.T) {
+	content := Content{
+		Title: "Pesanan dikirim",
+		Channels: map[string]ChannelContent{
+			"email": {Subject: "Rendered subject", Preview: "Rendered preview"},
+		},
+	}
+
+	got := content.WithVariants(map[string]ChannelContent{
+		"email": {Subject: "Override subject"},
+		"push":  {Body: "Override body"},
+	})
+
+	want := map[string]ChannelContent{
+		"email": {Subject: "Override subject", Preview: "Rendered preview"},
+		"push":  {Body: "Override body"},
+	}
+	if !reflect.DeepEqual(got.Channels, want) {
+		t.Errorf("Channels = %+v, want %+v", got.Channels, want)
+	}
+	if content.Channels["email"].Subject != "Rendered subject" {
+		t.Error("WithVariants() modified the original content")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		max  int
+		want string
+	}{
+		{
+			name: "fits",
+			text: "Pesanan dikirim",
+			max:  20,
+			want: "Pesanan dikirim",
+		},
+		{
+			name: "unlimited",
+			text: "Pesanan dikirim",
+			max:  0,
+			want: "Pesanan dikirim",
+		},
+		{
+			name: "cuts at a word boundary",
+			text: "Pesanan kamu sudah dikirim oleh kurir",
+			max:  20,
+			want: "Pesanan kamu sudah…",
+		},
+		{
+			name: "trims punctuation before the ellipsis",
+			text: "Pesanan, kamu sudah dikirim",
+			max:  10,
+			want: "Pesanan…",
+		},
+		{
+			name: "no word boundary nearby",
+			text: "Supercalifragilistic",
+			max:  10,
+			want: "Supercali…",
+		},
+		{
+			name: "counts characters, not bytes",
+			text: "ééééééééé",
+			max:  5,
+			want: "éééé…",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Truncate(tt.text, tt.max)
+			if got != tt.want {
+				t.Errorf("Truncate() = %q, want %q", got, tt.want)
+			}
+			if tt.max > 0 && utf8.RuneCountInString(got) > tt.max {
+				t.Errorf("Truncate() returned %d characters, max %d", utf8.RuneCountInString(got), tt.max)
+			}
+		})
+	}
+}
+
+func TestPushLimitsApply(t *testing.T) {
+	variant := PushLimits.Apply(ChannelContent{
+		Subject: strings.Repeat("judul ", 20),
+		Body:    strings.Repeat("pesan ", 50),
+		Preview: strings.Repeat("pratinjau ", 50),
+	})
+
+	if n := utf8.RuneCountInString(variant.Subject); n > PushLimits.SubjectMax {
+		t.Errorf("subject has %d characters, max %d", n, PushLimits.SubjectMax)
+	}
+	if n := utf8.RuneCountInString(variant.Body); n > PushLimits.BodyMax {
+		t.Errorf("body has %d characters, max %d", n, PushLimits.BodyMax)
+	}
+	if variant.Preview != strings.Repeat("pratinjau ", 50) {
+		t.Error("preview was truncated without a limit")
+	}
+}
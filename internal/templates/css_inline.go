@@ -0,0 +1,176 @@
+package templates
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	styleBlockPattern = regexp.MustCompile(`(?is)<style[^>]*>(.*?)</style>`)
+	cssCommentPattern = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	openTagPattern    = regexp.MustCompile(`<([a-zA-Z][a-zA-Z0-9]*)((?:\s+[^>]*?)?)(\s*/?)>`)
+	classAttrPattern  = regexp.MustCompile(`\sclass="([^"]*)"`)
+	styleAttrPattern  = regexp.MustCompile(`\sstyle="([^"]*)"`)
+	simpleSelector    = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9]*)?(?:\.([a-zA-Z0-9_-]+))?$`)
+)
+
+type cssRule struct {
+	tag         string
+	class       string
+	specificity int
+	order       int
+	decls       string
+}
+
+func (r cssRule) matches(tag string, classes []string) bool {
+	if r.tag != "" && !strings.EqualFold(r.tag, tag) {
+		return false
+	}
+	if r.class == "" {
+		return true
+	}
+	for _, c := range classes {
+		if c == r.class {
+			return true
+		}
+	}
+	return false
+}
+
+// InlineCSS moves the rules of <style> blocks into style attributes, since
+// Gmail and Outlook ignore or strip most stylesheet CSS. Only tag, .class and
+// tag.class selectors are supported; anything else is rejected so layout
+// authors notice instead of shipping unstyled email.
+func InlineCSS(html string) (string, error) {
+	var rules []cssRule
+
+	for _, block := range styleBlockPattern.FindAllStringSubmatch(html, -1) {
+		parsed, err := parseCSS(block[1], len(rules))
+		if err != nil {
+			return "", err
+		}
+		rules = append(rules, parsed...)
+	}
+
+	if len(rules) == 0 {
+		return html, nil
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].specificity != rules[j].specificity {
+			return rules[i].specificity < rules[j].specificity
+		}
+		return rules[i].order < rules[j].order
+	})
+
+	html = styleBlockPattern.ReplaceAllString(html, "")
+
+	return openTagPattern.ReplaceAllStringFunc(html, func(tag string) string {
+		parts := openTagPattern.FindStringSubmatch(tag)
+		name, attrs, closing := parts[1], parts[2], parts[3]
+
+		var classes []string
+		if m := classAttrPattern.FindStringSubmatch(attrs); m != nil {
+			classes = strings.Fields(m[1])
+		}
+
+		var decls []string
+		for _, rule := range rules {
+			if rule.matches(name, classes) {
+				decls = append(decls, rule.decls)
+			}
+		}
+		if len(decls) == 0 {
+			return tag
+		}
+
+		// Existing inline styles win over stylesheet rules
+		if m := styleAttrPattern.FindStringSubmatch(attrs); m != nil {
+			decls = append(decls, strings.TrimSuffix(strings.TrimSpace(m[1]), ";"))
+			attrs = styleAttrPattern.ReplaceAllString(attrs, "")
+		}
+
+		style := mergeDeclarations(decls) + ";"
+		return fmt.Sprintf(`<%s%s style="%s"%s>`, name, attrs, style, closing)
+	}), nil
+}
+
+func parseCSS(css string, offset int) ([]cssRule, error) {
+	var rules []cssRule
+
+	css = cssCommentPattern.ReplaceAllString(css, "")
+	for _, block := range strings.Split(css, "}") {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+
+		parts := strings.SplitN(block, "{", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid css rule: %q", block)
+		}
+
+		decls := normalizeDeclarations(parts[1])
+		if decls == "" {
+			continue
+		}
+
+		for _, selector := range strings.Split(parts[0], ",") {
+			selector = strings.TrimSpace(selector)
+			m := simpleSelector.FindStringSubmatch(selector)
+			if m == nil || selector == "" {
+				return nil, fmt.Errorf("unsupported css selector: %q", selector)
+			}
+
+			rule := cssRule{tag: m[1], class: m[2], order: offset + len(rules), decls: decls}
+			if rule.tag != "" {
+				rule.specificity++
+			}
+			if rule.class != "" {
+				rule.specificity += 10
+			}
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, nil
+}
+
+func normalizeDeclarations(decls string) string {
+	var result []string
+	for _, decl := range strings.Split(decls, ";") {
+		decl = strings.Join(strings.Fields(decl), " ")
+		if decl != "" {
+			result = append(result, decl)
+		}
+	}
+	return strings.Join(result, "; ")
+}
+
+// mergeDeclarations keeps the last value of each property, in first-seen order
+func mergeDeclarations(decls []string) string {
+	var order []string
+	values := make(map[string]string)
+
+	for _, group := range decls {
+		for _, decl := range strings.Split(group, ";") {
+			parts := strings.SplitN(decl, ":", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			prop := strings.ToLower(strings.TrimSpace(parts[0]))
+			if _, seen := values[prop]; !seen {
+				order = append(order, prop)
+			}
+			values[prop] = strings.TrimSpace(parts[1])
+		}
+	}
+
+	result := make([]string, 0, len(order))
+	for _, prop := range order {
+		result = append(result, prop+": "+values[prop])
+	}
+	return strings.Join(result, "; ")
+}
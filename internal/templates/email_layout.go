@@ -0,0 +1,169 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+// EmailAction is the call-to-action button rendered below the message
+type EmailAction struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// EmailSummaryRow is a single label/value row of the summary table
+type EmailSummaryRow struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// EmailContent is the per-notification content placed into the shared layout
+type EmailContent struct {
+	Subject string
+	Heading string
+	Preview string
+	Message string
+	Action  *EmailAction
+	Summary []EmailSummaryRow
+}
+
+// EmailLayout renders branded HTML emails with a derived plain-text alternative
+type EmailLayout struct {
+	brandName string
+	baseURL   string
+	tmpl      *template.Template
+}
+
+func NewEmailLayout(brandName, baseURL string) (*EmailLayout, error) {
+	tmpl, err := template.New("email").Parse(emailLayoutHTML)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse email layout: %w", err)
+	}
+
+	return &EmailLayout{
+		brandName: brandName,
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		tmpl:      tmpl,
+	}, nil
+}
+
+// Render returns the CSS-inlined HTML body and its plain-text alternative
+func (l *EmailLayout) Render(content EmailContent) (string, string, error) {
+	heading := content.Heading
+	if heading == "" {
+		heading = content.Subject
+	}
+
+	var action *EmailAction
+	if content.Action != nil && content.Action.URL != "" {
+		action = &EmailAction{
+			Label: content.Action.Label,
+			URL:   l.absoluteURL(content.Action.URL),
+		}
+	}
+
+	data := struct {
+		BrandName  string
+		BaseURL    string
+		Subject    string
+		Heading    string
+		Preview    string
+		Paragraphs []string
+		Action     *EmailAction
+		Summary    []EmailSummaryRow
+		Year       int
+	}{
+		BrandName:  l.brandName,
+		BaseURL:    l.baseURL,
+		Subject:    content.Subject,
+		Heading:    heading,
+		Preview:    content.Preview,
+		Paragraphs: splitParagraphs(content.Message),
+		Action:     action,
+		Summary:    content.Summary,
+		Year:       time.Now().Year(),
+	}
+
+	var buf bytes.Buffer
+	if err := l.tmpl.Execute(&buf, data); err != nil {
+		return "", "", fmt.Errorf("failed to render email layout: %w", err)
+	}
+
+	html, err := InlineCSS(buf.String())
+	if err != nil {
+		return "", "", err
+	}
+
+	return html, HTMLToText(html), nil
+}
+
+func (l *EmailLayout) absoluteURL(url string) string {
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") || l.baseURL == "" {
+		return url
+	}
+	return l.baseURL + "/" + strings.TrimLeft(url, "/")
+}
+
+func splitParagraphs(message string) []string {
+	var paragraphs []string
+	for _, p := range strings.Split(message, "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	return paragraphs
+}
+
+const emailLayoutHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1.0">
+<title>{{.Subject}}</title>
+<style>
+body { margin: 0; padding: 0; background-color: #f4f4f7; font-family: Arial, Helvetica, sans-serif; color: #333333; }
+table { border-collapse: collapse; }
+.preheader { display: none; max-height: 0; overflow: hidden; mso-hide: all; font-size: 1px; line-height: 1px; color: #f4f4f7; opacity: 0; }
+.wrapper { width: 100%; background-color: #f4f4f7; padding: 24px 0; }
+.container { width: 600px; max-width: 600px; margin: 0 auto; background-color: #ffffff; }
+.header { background-color: #e4572e; padding: 20px 32px; }
+.brand { color: #ffffff; font-size: 22px; font-weight: bold; text-decoration: none; }
+.content { padding: 32px; }
+h1 { margin: 0 0 16px 0; font-size: 20px; color: #222222; }
+p { margin: 0 0 16px 0; font-size: 15px; line-height: 22px; }
+.summary { width: 100%; margin: 8px 0 24px 0; border: 1px solid #e6e6e6; }
+.summary-label { padding: 10px 12px; font-size: 14px; color: #666666; border-bottom: 1px solid #e6e6e6; }
+.summary-value { padding: 10px 12px; font-size: 14px; font-weight: bold; text-align: right; border-bottom: 1px solid #e6e6e6; }
+.button { display: inline-block; padding: 12px 24px; background-color: #e4572e; color: #ffffff; font-size: 15px; font-weight: bold; text-decoration: none; border-radius: 4px; }
+.footer { padding: 20px 32px; text-align: center; }
+.footer-text { margin: 0 0 8px 0; font-size: 12px; line-height: 18px; color: #999999; }
+.footer-link { color: #999999; text-decoration: underline; }
+</style>
+</head>
+<body>
+{{if .Preview}}<div class="preheader">{{.Preview}}</div>
+{{end}}<table class="wrapper" role="presentation" width="100%">
+<tr><td>
+<table class="container" role="presentation" width="600" align="center">
+<tr><td class="header"><a class="brand" href="{{.BaseURL}}">{{.BrandName}}</a></td></tr>
+<tr><td class="content">
+<h1>{{.Heading}}</h1>
+{{range .Paragraphs}}<p>{{.}}</p>
+{{end}}{{if .Summary}}<table class="summary" role="presentation" width="100%">
+{{range .Summary}}<tr><td class="summary-label">{{.Label}}</td><td class="summary-value">{{.Value}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Action}}<p><a class="button" href="{{.Action.URL}}">{{.Action.Label}}</a></p>
+{{end}}</td></tr>
+<tr><td class="footer">
+<p class="footer-text">Email ini dikirim otomatis oleh {{.BrandName}}. Mohon tidak membalas email ini.</p>
+<p class="footer-text">&copy; {{.Year}} {{.BrandName}} &middot; <a class="footer-link" href="{{.BaseURL}}/settings/notifications">Atur notifikasi</a></p>
+</td></tr>
+</table>
+</td></tr>
+</table>
+</body>
+</html>
+`
@@ -0,0 +1,133 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInlineCSS(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "no stylesheet",
+			html: `<p class="lead">Halo</p>`,
+			want: `<p class="lead">Halo</p>`,
+		},
+		{
+			name: "tag and class rules",
+			html: `<style>p { margin: 0; color: #333 } .lead { color: red; }</style><p class="lead">Halo</p><p>Hai</p>`,
+			want: `<p class="lead" style="margin: 0; color: red;">Halo</p><p style="margin: 0; color: #333;">Hai</p>`,
+		},
+		{
+			name: "class beats tag regardless of order",
+			html: `<style>.lead { color: red } p { color: blue }</style><p class="lead">Halo</p>`,
+			want: `<p class="lead" style="color: red;">Halo</p>`,
+		},
+		{
+			name: "inline style wins",
+			html: `<style>p { color: blue; margin: 0 }</style><p style="color: green">Halo</p>`,
+			want: `<p style="color: green; margin: 0;">Halo</p>`,
+		},
+		{
+			name: "grouped selectors and comments",
+			html: `<style>/* headings */ h1, td.cell { padding: 4px }</style><h1>A</h1><td class="cell">B</td><td>C</td>`,
+			want: `<h1 style="padding: 4px;">A</h1><td class="cell" style="padding: 4px;">B</td><td>C</td>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := InlineCSS(tt.html)
+			if err != nil {
+				t.Fatalf("InlineCSS() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("InlineCSS() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInlineCSSRejectsUnsupportedSelectors(t *testing.T) {
+	for _, selector := range []string{"table td", "a:hover", "#header", "p > a"} {
+		html := "<style>" + selector + " { color: red }</style><p>Halo</p>"
+		if _, err := InlineCSS(html); err == nil {
+			t.Errorf("InlineCSS() accepted selector %q", selector)
+		}
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	html := `<html><head><title>Pesanan</title></head><body>
+<div class="preheader">Pratinjau</div>
+<h1>Pesanan dikirim</h1>
+<p>Halo Budi,   pesanan   kamu &amp; paket sudah dikirim.</p>
+<table><tr><td>Kurir</td><td>JNE</td></tr></table>
+<p><a href="https://tokohobby.id/orders/1">Lacak pesanan</a></p>
+<p><a href="https://tokohobby.id">https://tokohobby.id</a></p>
+</body></html>`
+
+	want := `Pesanan dikirim
+
+Halo Budi, pesanan kamu & paket sudah dikirim.
+
+Kurir JNE
+
+Lacak pesanan (https://tokohobby.id/orders/1)
+
+https://tokohobby.id
+`
+
+	if got := HTMLToText(html); got != want {
+		t.Errorf("HTMLToText() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestEmailLayoutRender(t *testing.T) {
+	layout, err := NewEmailLayout("TokoHobby", "https://tokohobby.id/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	html, text, err := layout.Render(EmailContent{
+		Subject: "Pesanan dikirim",
+		Preview: "Resi JNE123",
+		Message: "Halo <Budi>,\n\nPesanan kamu sudah dikirim.",
+		Action:  &EmailAction{Label: "Lacak pesanan", URL: "/orders/1"},
+		Summary: []EmailSummaryRow{{Label: "Kurir", Value: "JNE"}},
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"<h1 style=",
+		"Halo &lt;Budi&gt;,</p>",
+		`href="https://tokohobby.id/orders/1"`,
+		`<div class="preheader" style="display: none;`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("html does not contain %q", want)
+		}
+	}
+	if strings.Contains(html, "<style") {
+		t.Error("html still has a <style> block")
+	}
+
+	for _, want := range []string{
+		"Pesanan dikirim\n",
+		"Halo <Budi>,\n",
+		"Kurir JNE\n",
+		"Lacak pesanan (https://tokohobby.id/orders/1)\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text does not contain %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "Resi JNE123") {
+		t.Error("text contains the preheader")
+	}
+}
@@ -0,0 +1,108 @@
+package templates
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// filters format a variable as {{variable|filter}}
+var filters = map[string]func(interface{}) string{
+	"rupiah": func(value interface{}) string {
+		amount, ok := toFloat(value)
+		if !ok {
+			return formatValue(value)
+		}
+		return FormatRupiah(amount)
+	},
+	"date": func(value interface{}) string {
+		if t, ok := toTime(value); ok {
+			return t.Format("02 Jan 2006")
+		}
+		return formatValue(value)
+	},
+	"datetime": func(value interface{}) string {
+		if t, ok := toTime(value); ok {
+			return t.Format("02 Jan 2006 15:04")
+		}
+		return formatValue(value)
+	},
+	"upper": func(value interface{}) string {
+		return strings.ToUpper(formatValue(value))
+	},
+}
+
+func applyFilter(filter string, value interface{}) string {
+	if fn, ok := filters[filter]; ok {
+		return fn(value)
+	}
+	return formatValue(value)
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case time.Time:
+		return v.Format("02 Jan 2006 15:04")
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// FormatRupiah formats an amount as Indonesian Rupiah, e.g. "Rp 1.500.000"
+func FormatRupiah(amount float64) string {
+	digits := strconv.FormatFloat(math.Round(amount), 'f', 0, 64)
+
+	negative := strings.HasPrefix(digits, "-")
+	digits = strings.TrimPrefix(digits, "-")
+
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(d)
+	}
+
+	if negative {
+		return "-Rp " + b.String()
+	}
+	return "Rp " + b.String()
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func toTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, !v.IsZero()
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		return t, err == nil
+	default:
+		return time.Time{}, false
+	}
+}
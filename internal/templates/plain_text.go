@@ -0,0 +1,58 @@
+package templates
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	headPattern      = regexp.MustCompile(`(?is)<head[^>]*>.*?</head>`)
+	preheaderPattern = regexp.MustCompile(`(?is)<div class="preheader"[^>]*>.*?</div>`)
+	anchorPattern    = regexp.MustCompile(`(?is)<a\s[^>]*?href="([^"]*)"[^>]*>(.*?)</a>`)
+	lineBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|h[1-6]|tr|table|li)>`)
+	cellPattern      = regexp.MustCompile(`(?i)</t[dh]>`)
+	listItemPattern  = regexp.MustCompile(`(?i)<li[^>]*>`)
+	tagPattern       = regexp.MustCompile(`(?s)<[^>]+>`)
+	blankLinePattern = regexp.MustCompile(`\n{3,}`)
+)
+
+// HTMLToText derives a readable plain-text version of an HTML email
+func HTMLToText(body string) string {
+	text := headPattern.ReplaceAllString(body, "")
+	text = styleBlockPattern.ReplaceAllString(text, "")
+	text = preheaderPattern.ReplaceAllString(text, "")
+
+	text = anchorPattern.ReplaceAllStringFunc(text, func(anchor string) string {
+		m := anchorPattern.FindStringSubmatch(anchor)
+		href := html.UnescapeString(m[1])
+		label := strings.TrimSpace(tagPattern.ReplaceAllString(m[2], ""))
+
+		switch {
+		case href == "" || strings.HasPrefix(href, "#"):
+			return label
+		case label == "" || html.UnescapeString(label) == href:
+			return href
+		default:
+			return label + " (" + href + ")"
+		}
+	})
+
+	text = lineBreakPattern.ReplaceAllString(text, "\n")
+	text = cellPattern.ReplaceAllString(text, "  ")
+	text = listItemPattern.ReplaceAllString(text, "- ")
+	text = tagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	text = strings.Join(lines, "\n")
+
+	// Paragraphs are separated by exactly one blank line
+	text = strings.ReplaceAll(text, "\n", "\n\n")
+	text = blankLinePattern.ReplaceAllString(text, "\n\n")
+
+	return strings.TrimSpace(text) + "\n"
+}
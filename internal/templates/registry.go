@@ -0,0 +1,69 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrUnknownTemplate is returned when a template name is not registered
+var ErrUnknownTemplate = errors.New("unknown template")
+
+// Registry holds templates validated against their event schemas
+type Registry struct {
+	mu        sync.RWMutex
+	templates map[string]Template
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		templates: make(map[string]Template),
+	}
+}
+
+// Register validates the template against the fields of event and stores it
+func (r *Registry) Register(tmpl Template, event interface{}) error {
+	return r.RegisterWithSchema(tmpl, SchemaOf(event))
+}
+
+// RegisterWithSchema validates the template against schema and stores it
+func (r *Registry) RegisterWithSchema(tmpl Template, schema Schema) error {
+	if tmpl.Name == "" {
+		return fmt.Errorf("template name is required")
+	}
+
+	if err := tmpl.Validate(schema); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.templates[tmpl.Name]; exists {
+		return fmt.Errorf("template %q already registered", tmpl.Name)
+	}
+	r.templates[tmpl.Name] = tmpl
+
+	return nil
+}
+
+// Get returns a registered template by name
+func (r *Registry) Get(name string) (Template, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tmpl, ok := r.templates[name]
+	if !ok {
+		return Template{}, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+	return tmpl, nil
+}
+
+// Render renders a registered template with the given data
+func (r *Registry) Render(name string, data map[string]interface{}) (Content, error) {
+	tmpl, err := r.Get(name)
+	if err != nil {
+		return Content{}, err
+	}
+	return tmpl.Render(data)
+}
@@ -0,0 +1,277 @@
+package templates
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// placeholderPattern matches {{variable}} and {{variable|filter}}
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*(?:\|\s*([a-z_]+)\s*)?\}\}`)
+
+// Template is a named title/message pair with {{variable}} placeholders.
+// Channels optionally overrides the subject, body or preview per channel.
+// ProviderTemplates maps a channel to the provider's pre-approved template
+// used instead of free text, e.g. WhatsApp outside the 24-hour session.
+type Template struct {
+	Name              string                      `json:"name"`
+	Title             string                      `json:"title"`
+	Message           string                      `json:"message"`
+	Channels          map[string]ChannelContent   `json:"channels,omitempty"`
+	ProviderTemplates map[string]ProviderTemplate `json:"provider_templates,omitempty"`
+	Defaults          map[string]string           `json:"defaults,omitempty"`
+}
+
+// ProviderTemplate references a template approved by a provider. Parameters
+// are interpolated like the other texts and fill the provider template's
+// positional placeholders in order.
+type ProviderTemplate struct {
+	Name       string   `json:"name"`
+	Language   string   `json:"language"`
+	Parameters []string `json:"parameters,omitempty"`
+}
+
+// Schema is the set of variables an event declares
+type Schema map[string]bool
+
+// MissingVariableError is returned when rendering without a required variable
+type MissingVariableError struct {
+	Template  string
+	Variables []string
+}
+
+func (e *MissingVariableError) Error() string {
+	return fmt.Sprintf("template %q: missing variables: %s", e.Template, strings.Join(e.Variables, ", "))
+}
+
+// UndeclaredVariableError is returned when a template uses a variable the event does not declare
+type UndeclaredVariableError struct {
+	Template  string
+	Variables []string
+}
+
+func (e *UndeclaredVariableError) Error() string {
+	return fmt.Sprintf("template %q: variables not declared by event: %s", e.Template, strings.Join(e.Variables, ", "))
+}
+
+// SchemaOf builds a schema from the json tags of an event struct
+func SchemaOf(event interface{}) Schema {
+	schema := make(Schema)
+
+	t := reflect.TypeOf(event)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return schema
+	}
+
+	collectFields(t, schema)
+	return schema
+}
+
+// SchemaFromFields builds a schema from a list of field names
+func SchemaFromFields(fields []string) Schema {
+	schema := make(Schema, len(fields))
+	for _, field := range fields {
+		schema[field] = true
+	}
+	return schema
+}
+
+func collectFields(t reflect.Type, schema Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			collectFields(field.Type, schema)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		schema[name] = true
+	}
+}
+
+// Variables returns the placeholder names used in title, message and variants
+func (t Template) Variables() []string {
+	return VariablesIn(t.texts()...)
+}
+
+func (t Template) texts() []string {
+	texts := []string{t.Title, t.Message}
+	for _, variant := range t.Channels {
+		texts = append(texts, variant.Subject, variant.Body, variant.Preview)
+	}
+	for _, provider := range t.ProviderTemplates {
+		texts = append(texts, provider.Parameters...)
+	}
+	return texts
+}
+
+// Validate checks that every placeholder is declared by the schema or has a
+// default, that every filter exists and that provider templates are named
+func (t Template) Validate(schema Schema) error {
+	for channel, provider := range t.ProviderTemplates {
+		if provider.Name == "" || provider.Language == "" {
+			return fmt.Errorf("template %q: %s provider template needs a name and language", t.Name, channel)
+		}
+	}
+	return ValidateTexts(t.Name, schema, t.Defaults, t.texts()...)
+}
+
+// Render interpolates title, message and channel variants, failing if a
+// variable has no value and no default
+func (t Template) Render(data map[string]interface{}) (Content, error) {
+	in := &interpolator{data: data, defaults: t.Defaults}
+
+	content := Content{
+		Title:   in.interpolate(t.Title),
+		Message: in.interpolate(t.Message),
+	}
+
+	if len(t.Channels) > 0 {
+		content.Channels = make(map[string]ChannelContent, len(t.Channels))
+		for channel, variant := range t.Channels {
+			content.Channels[channel] = ChannelContent{
+				Subject: in.interpolate(variant.Subject),
+				Body:    in.interpolate(variant.Body),
+				Preview: in.interpolate(variant.Preview),
+			}
+		}
+	}
+
+	if len(t.ProviderTemplates) > 0 {
+		content.ProviderTemplates = make(map[string]ProviderTemplate, len(t.ProviderTemplates))
+		for channel, provider := range t.ProviderTemplates {
+			params := make([]string, len(provider.Parameters))
+			for i, param := range provider.Parameters {
+				params[i] = in.interpolate(param)
+			}
+			provider.Parameters = params
+			content.ProviderTemplates[channel] = provider
+		}
+	}
+
+	if err := in.err(t.Name); err != nil {
+		return Content{}, err
+	}
+
+	return content, nil
+}
+
+// VariablesIn returns the sorted placeholder names used across texts
+func VariablesIn(texts ...string) []string {
+	seen := make(map[string]bool)
+	var vars []string
+
+	for _, text := range texts {
+		for _, match := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				vars = append(vars, match[1])
+			}
+		}
+	}
+
+	sort.Strings(vars)
+	return vars
+}
+
+// ValidateTexts checks the placeholders of arbitrary texts against a schema
+func ValidateTexts(name string, schema Schema, defaults map[string]string, texts ...string) error {
+	for _, text := range texts {
+		for _, match := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+			if filter := match[2]; filter != "" {
+				if _, ok := filters[filter]; !ok {
+					return fmt.Errorf("template %q: unknown filter %q", name, filter)
+				}
+			}
+		}
+	}
+
+	var undeclared []string
+	for _, variable := range VariablesIn(texts...) {
+		if schema[variable] {
+			continue
+		}
+		if _, ok := defaults[variable]; ok {
+			continue
+		}
+		undeclared = append(undeclared, variable)
+	}
+
+	if len(undeclared) > 0 {
+		return &UndeclaredVariableError{Template: name, Variables: undeclared}
+	}
+	return nil
+}
+
+// Interpolate renders a single text, failing if a variable has no value and no default
+func Interpolate(name, text string, data map[string]interface{}, defaults map[string]string) (string, error) {
+	in := &interpolator{data: data, defaults: defaults}
+	result := in.interpolate(text)
+	if err := in.err(name); err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+type interpolator struct {
+	data     map[string]interface{}
+	defaults map[string]string
+	missing  []string
+}
+
+func (in *interpolator) interpolate(text string) string {
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		match := placeholderPattern.FindStringSubmatch(placeholder)
+		name, filter := match[1], match[2]
+
+		value, ok := in.data[name]
+		if ok && value != nil && value != "" {
+			return applyFilter(filter, value)
+		}
+		if def, hasDefault := in.defaults[name]; hasDefault {
+			return def
+		}
+		if ok && value != nil {
+			return ""
+		}
+
+		in.missing = append(in.missing, name)
+		return placeholder
+	})
+}
+
+func (in *interpolator) err(name string) error {
+	if len(in.missing) == 0 {
+		return nil
+	}
+	sort.Strings(in.missing)
+	return &MissingVariableError{Template: name, Variables: dedupe(in.missing)}
+}
+
+func dedupe(sorted []string) []string {
+	result := sorted[:0]
+	for i, s := range sorted {
+		if i == 0 || s != sorted[i-1] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
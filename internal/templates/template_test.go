@@ -0,0 +1,129 @@
+package templates
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type orderEvent struct {
+	OrderID     string  `json:"order_id"`
+	TotalAmount float64 `json:"total_amount,omitempty"`
+	Internal    string  `json:"-"`
+	Courier     string
+	note        string
+}
+
+func TestSchemaOf(t *testing.T) {
+	got := SchemaOf(&orderEvent{})
+	want := Schema{"order_id": true, "total_amount": true, "Courier": true}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SchemaOf() = %v, want %v", got, want)
+	}
+}
+
+func TestTemplateValidate(t *testing.T) {
+	schema := SchemaOf(orderEvent{})
+
+	tests := []struct {
+		name       string
+		tmpl       Template
+		undeclared []string
+	}{
+		{
+			name: "declared variables",
+			tmpl: Template{Name: "order_created", Title: "Pesanan {{order_id}}", Message: "Total {{ total_amount }}"},
+		},
+		{
+			name:       "undeclared variables",
+			tmpl:       Template{Name: "order_created", Title: "Pesanan {{order_number}}", Message: "Halo {{buyer_name}}"},
+			undeclared: []string{"buyer_name", "order_number"},
+		},
+		{
+			name: "undeclared variable with a default",
+			tmpl: Template{
+				Name:     "order_created",
+				Title:    "Halo {{buyer_name}}",
+				Defaults: map[string]string{"buyer_name": "Pelanggan"},
+			},
+		},
+		{
+			name: "undeclared variable in a channel variant",
+			tmpl: Template{
+				Name:     "order_created",
+				Title:    "Pesanan {{order_id}}",
+				Channels: map[string]ChannelContent{"email": {Preview: "Resi {{tracking_number}}"}},
+			},
+			undeclared: []string{"tracking_number"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.tmpl.Validate(schema)
+
+			if tt.undeclared == nil {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+
+			var undeclaredErr *UndeclaredVariableError
+			if !errors.As(err, &undeclaredErr) {
+				t.Fatalf("Validate() error = %v, want an UndeclaredVariableError", err)
+			}
+			if !reflect.DeepEqual(undeclaredErr.Variables, tt.undeclared) {
+				t.Errorf("undeclared variables = %v, want %v", undeclaredErr.Variables, tt.undeclared)
+			}
+		})
+	}
+}
+
+func TestTemplateRender(t *testing.T) {
+	tmpl := Template{
+		Name:     "order_shipped",
+		Title:    "Pesanan {{order_id}} dikirim",
+		Message:  "Halo {{buyer_name}}, kurir {{courier}} membawa pesanan {{order_id}}",
+		Defaults: map[string]string{"buyer_name": "Pelanggan"},
+	}
+
+	content, err := tmpl.Render(map[string]interface{}{"order_id": "ORD-1", "courier": "JNE"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if want := "Pesanan ORD-1 dikirim"; content.Title != want {
+		t.Errorf("Title = %q, want %q", content.Title, want)
+	}
+	if want := "Halo Pelanggan, kurir JNE membawa pesanan ORD-1"; content.Message != want {
+		t.Errorf("Message = %q, want %q", content.Message, want)
+	}
+
+	_, err = tmpl.Render(map[string]interface{}{"courier": "JNE"})
+	var missingErr *MissingVariableError
+	if !errors.As(err, &missingErr) {
+		t.Fatalf("Render() error = %v, want a MissingVariableError", err)
+	}
+	if want := []string{"order_id"}; !reflect.DeepEqual(missingErr.Variables, want) {
+		t.Errorf("missing variables = %v, want %v", missingErr.Variables, want)
+	}
+}
+
+func TestRegistryRegister(t *testing.T) {
+	registry := NewRegistry()
+	tmpl := Template{Name: "order_created", Title: "Pesanan {{order_id}}"}
+
+	if err := registry.Register(tmpl, orderEvent{}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := registry.Register(tmpl, orderEvent{}); err == nil {
+		t.Error("Register() accepted a duplicate name")
+	}
+	if err := registry.Register(Template{Name: "bad", Title: "{{unknown}}"}, orderEvent{}); err == nil {
+		t.Error("Register() accepted an undeclared variable")
+	}
+	if _, err := registry.Get("bad"); !errors.Is(err, ErrUnknownTemplate) {
+		t.Errorf("Get() error = %v, want ErrUnknownTemplate", err)
+	}
+}